@@ -0,0 +1,293 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+// Package blake2b implements the BLAKE2b hash function
+// defined in RFC 7693. BLAKE2b produces digests of up to
+// 64 byte, supports keying (for use as a MAC), salting and
+// personalization, and - through the tree parameters added
+// to Params - tree hashing and the BLAKE2X extendable output
+// construction (see the blake2x.go file in this package).
+package blake2b
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash"
+)
+
+const (
+	// BlockSize is the block size of BLAKE2b in byte.
+	BlockSize = 128
+	// HashSize is the maximum size, in byte, of a BLAKE2b checksum.
+	HashSize = 64
+)
+
+var iv = [8]uint64{
+	0x6a09e667f3bcc908, 0xbb67ae8584caa73b, 0x3c6ef372fe94f82b, 0xa54ff53a5f1d36f1,
+	0x510e527fade682d1, 0x9b05688c2b3e6c1f, 0x1f83d9abfb41bd6b, 0x5be0cd19137e2179,
+}
+
+var sigma = [12][16]byte{
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	{14, 10, 4, 8, 9, 15, 13, 6, 1, 12, 0, 2, 11, 7, 5, 3},
+	{11, 8, 12, 0, 5, 2, 15, 13, 10, 14, 3, 6, 7, 1, 9, 4},
+	{7, 9, 3, 1, 13, 12, 11, 14, 2, 6, 5, 10, 4, 0, 15, 8},
+	{9, 0, 5, 7, 2, 4, 10, 15, 14, 1, 11, 12, 6, 8, 3, 13},
+	{2, 12, 6, 10, 0, 11, 8, 3, 4, 13, 7, 5, 15, 14, 1, 9},
+	{12, 5, 1, 15, 14, 13, 4, 10, 0, 7, 6, 3, 9, 2, 8, 11},
+	{13, 11, 7, 14, 12, 1, 3, 9, 5, 0, 15, 4, 8, 6, 2, 10},
+	{6, 15, 14, 9, 11, 3, 0, 8, 12, 2, 13, 7, 1, 4, 10, 5},
+	{10, 2, 8, 4, 7, 6, 1, 5, 15, 11, 9, 14, 3, 12, 13, 0},
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	{14, 10, 4, 8, 9, 15, 13, 6, 1, 12, 0, 2, 11, 7, 5, 3},
+}
+
+// Params contains the BLAKE2b parameters. Besides the digest
+// size and an optional key, salt and personalization, Params
+// also carries the tree-hashing parameter block fields defined
+// by RFC 7693 section 2.10. A zero-valued Params describes
+// sequential (non-tree) hashing with the default digest size,
+// which is the behaviour most callers want.
+type Params struct {
+	HashSize int    // The hash size of BLAKE2b in bytes (default and max. is 64)
+	Key      []byte // The key for keyed hashing (up to 64 bytes)
+	Salt     []byte // The salt (up to 16 bytes)
+	Personal []byte // The personalization (up to 16 bytes)
+
+	// Tree hashing parameters. Leave these at their zero value
+	// for sequential hashing. See blake2x.go for how they are
+	// used to implement the BLAKE2X XOF construction.
+	Fanout     uint8  // The number of leaves in a level, 0 or 1 means sequential mode
+	Depth      uint8  // The maximal depth of the tree, 1 means sequential mode
+	LeafSize   uint32 // The maximal byte length of a leaf
+	NodeOffset uint64 // The node offset (the leaf or inner node's position in the tree)
+	NodeDepth  uint8  // The node depth (0 for leaves)
+	InnerSize  uint8  // The inner hash size used for chaining values (up to 64 bytes)
+
+	// XofLength is the BLAKE2X (see blake2x.go) output length in byte.
+	// It is 0 for regular, non-XOF hashing. UnknownXofLength marks a
+	// XOF of unbounded / not-yet-known output length.
+	XofLength uint32
+
+	// xofLeaf marks this Params as describing a BLAKE2X per-block leaf
+	// hash. Unlike every other zero-valued Fanout/Depth pair, which
+	// block() promotes to the sequential-mode default of 1, 1, a XOF
+	// leaf's Fanout and Depth must stay literally 0 as required by the
+	// BLAKE2X construction. Only nextBlock (blake2x.go) sets this.
+	xofLeaf bool
+}
+
+// UnknownXofLength marks a BLAKE2X output of unknown length, as
+// defined by the BLAKE2X specification.
+const UnknownXofLength = 1<<32 - 1
+
+// verifyParams checks whether p describes a valid parameter
+// set and returns a non-nil error otherwise.
+func verifyParams(p *Params) error {
+	if p.HashSize < 0 || p.HashSize > HashSize {
+		return errors.New("blake2b: invalid hash size")
+	}
+	if len(p.Key) > HashSize {
+		return errors.New("blake2b: invalid key size")
+	}
+	if len(p.Salt) > 16 {
+		return errors.New("blake2b: invalid salt size")
+	}
+	if len(p.Personal) > 16 {
+		return errors.New("blake2b: invalid personalization size")
+	}
+	if p.InnerSize > HashSize {
+		return errors.New("blake2b: invalid inner hash size")
+	}
+	if (p.Fanout > 1 || p.Depth > 1) && p.InnerSize == 0 {
+		return errors.New("blake2b: tree hashing requires a non-zero inner hash size")
+	}
+	return nil
+}
+
+// block builds the 64 byte BLAKE2b parameter block from p.
+func (p *Params) block() [64]byte {
+	var block [64]byte
+
+	hashSize := p.HashSize
+	if hashSize == 0 {
+		hashSize = HashSize
+	}
+	block[0] = byte(hashSize)
+	block[1] = byte(len(p.Key))
+	fanout, depth := p.Fanout, p.Depth
+	if !p.xofLeaf && fanout == 0 && depth == 0 {
+		fanout, depth = 1, 1
+	}
+	block[2] = fanout
+	block[3] = depth
+	binary.LittleEndian.PutUint32(block[4:8], p.LeafSize)
+
+	// The node offset field is 8 byte wide. BLAKE2Xb overlays the
+	// 32 bit XOF output length on top of the high order 32 bits,
+	// as specified by the BLAKE2X construction.
+	binary.LittleEndian.PutUint32(block[8:12], uint32(p.NodeOffset))
+	binary.LittleEndian.PutUint32(block[12:16], p.XofLength)
+
+	block[16] = p.NodeDepth
+	block[17] = p.InnerSize
+	copy(block[32:48], p.Salt)
+	copy(block[48:64], p.Personal)
+	return block
+}
+
+// digest implements hash.Hash for BLAKE2b.
+type digest struct {
+	hashSize int
+	h        [8]uint64
+	t0, t1   uint64
+	f0       uint64
+
+	buf   [BlockSize]byte
+	buflen int
+
+	key []byte
+}
+
+// New returns a new hash.Hash computing the BLAKE2b checksum
+// with the given parameters. A nil Params is equivalent to
+// new(Params), i.e. an unkeyed 64 byte digest.
+func New(p *Params) (hash.Hash, error) {
+	if p == nil {
+		p = new(Params)
+	}
+	if err := verifyParams(p); err != nil {
+		return nil, err
+	}
+
+	hashSize := p.HashSize
+	if hashSize == 0 {
+		hashSize = HashSize
+	}
+
+	d := &digest{hashSize: hashSize}
+	block := p.block()
+
+	d.h = iv
+	for i := range d.h {
+		d.h[i] ^= binary.LittleEndian.Uint64(block[i*8 : i*8+8])
+	}
+
+	if len(p.Key) > 0 {
+		d.key = make([]byte, BlockSize)
+		copy(d.key, p.Key)
+		d.Write(d.key)
+	}
+	return d, nil
+}
+
+// Sum returns the BLAKE2b checksum of msg using the given
+// parameters.
+func Sum(msg []byte, p *Params) ([]byte, error) {
+	h, err := New(p)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(msg)
+	return h.Sum(nil), nil
+}
+
+func (d *digest) BlockSize() int { return BlockSize }
+
+func (d *digest) Size() int { return d.hashSize }
+
+func (d *digest) Reset() {
+	panic("blake2b: Reset is not supported - create a new hash instead")
+}
+
+func (d *digest) Write(p []byte) (n int, err error) {
+	n = len(p)
+	if d.buflen > 0 {
+		free := BlockSize - d.buflen
+		if free > len(p) {
+			free = len(p)
+		}
+		copy(d.buf[d.buflen:], p[:free])
+		d.buflen += free
+		p = p[free:]
+		if len(p) == 0 {
+			return
+		}
+		d.addLength(BlockSize)
+		compress(d, d.buf[:], 0)
+		d.buflen = 0
+	}
+	for len(p) > BlockSize {
+		d.addLength(BlockSize)
+		compress(d, p[:BlockSize], 0)
+		p = p[BlockSize:]
+	}
+	d.buflen = copy(d.buf[:], p)
+	return
+}
+
+func (d *digest) addLength(n uint64) {
+	d.t0 += n
+	if d.t0 < n {
+		d.t1++
+	}
+}
+
+func (d *digest) Sum(b []byte) []byte {
+	cpy := *d
+	cpy.addLength(uint64(cpy.buflen))
+	for i := cpy.buflen; i < BlockSize; i++ {
+		cpy.buf[i] = 0
+	}
+	cpy.f0 = ^uint64(0)
+	compress(&cpy, cpy.buf[:], cpy.f0)
+
+	var out [HashSize]byte
+	for i, v := range cpy.h {
+		binary.LittleEndian.PutUint64(out[i*8:i*8+8], v)
+	}
+	return append(b, out[:cpy.hashSize]...)
+}
+
+func rotr64(x uint64, n uint) uint64 { return (x >> n) | (x << (64 - n)) }
+
+// compress absorbs one 128 byte message block into d, updating
+// the internal counter by t (already added via addLength by the
+// caller) and finalizing the state if f is the all-ones mask.
+func compress(d *digest, block []byte, f uint64) {
+	var m [16]uint64
+	for i := range m {
+		m[i] = binary.LittleEndian.Uint64(block[i*8 : i*8+8])
+	}
+
+	v := [16]uint64{
+		d.h[0], d.h[1], d.h[2], d.h[3], d.h[4], d.h[5], d.h[6], d.h[7],
+		iv[0], iv[1], iv[2], iv[3], iv[4] ^ d.t0, iv[5] ^ d.t1, iv[6] ^ f, iv[7],
+	}
+
+	g := func(a, b, c, d2 int, x, y uint64) {
+		v[a] += v[b] + x
+		v[d2] = rotr64(v[d2]^v[a], 32)
+		v[c] += v[d2]
+		v[b] = rotr64(v[b]^v[c], 24)
+		v[a] += v[b] + y
+		v[d2] = rotr64(v[d2]^v[a], 16)
+		v[c] += v[d2]
+		v[b] = rotr64(v[b]^v[c], 63)
+	}
+
+	for _, s := range sigma {
+		g(0, 4, 8, 12, m[s[0]], m[s[1]])
+		g(1, 5, 9, 13, m[s[2]], m[s[3]])
+		g(2, 6, 10, 14, m[s[4]], m[s[5]])
+		g(3, 7, 11, 15, m[s[6]], m[s[7]])
+		g(0, 5, 10, 15, m[s[8]], m[s[9]])
+		g(1, 6, 11, 12, m[s[10]], m[s[11]])
+		g(2, 7, 8, 13, m[s[12]], m[s[13]])
+		g(3, 4, 9, 14, m[s[14]], m[s[15]])
+	}
+
+	for i := 0; i < 8; i++ {
+		d.h[i] ^= v[i] ^ v[i+8]
+	}
+}
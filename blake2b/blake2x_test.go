@@ -0,0 +1,101 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package blake2b
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"testing"
+)
+
+// TestXOFKnownAnswer checks NewXOF's output against a vector produced
+// by golang.org/x/crypto/blake2b's BLAKE2Xb implementation, to catch
+// any deviation from the BLAKE2X reference construction that a purely
+// internal round-trip test (like TestXOFChunkedRead) cannot.
+func TestXOFKnownAnswer(t *testing.T) {
+	const want = "3cf7066cde61289e81a41290553cc63532f04754ea033ad71b1358de66b4174" +
+		"516c3e8fa2ed25fac253b0c0190e6505c241070da37b51d8ce6f906d0b1554a0" +
+		"bf9de1b28441e1c9b09ec9d082eb23ead0b86432c371eb9782e91eaf0900ef3b" +
+		"9fd98e14b9c9468cb8dee8fbaf5229015a03f1b0debd6547fb2dbead4994e0bf" +
+		"f5192ab0956882cdd41c99e9aaf4be8149fdfd265931c0f5b991ccdcdb9eebff" +
+		"d5aa1e6aedd63641c366fd17bd8dfd24242fb6a73e93058786b265905a5f0c36" +
+		"9c6e04b826eee562e0207c73cf246aa257ae5a84816fac32fd4d86a49abd3eb9" +
+		"3bd28132140a3374e251449937ceb8ba536d89ea02604ac8ce94e10a15a19875" +
+		"7"
+
+	x, err := NewXOF(&Params{XofLength: 256})
+	if err != nil {
+		t.Fatalf("Failed to create XOF: %s", err)
+	}
+	x.Write([]byte("abc"))
+
+	out := make([]byte, 256)
+	if _, err := io.ReadFull(x, out); err != nil {
+		t.Fatalf("Failed to read from XOF: %s", err)
+	}
+	if got := hex.EncodeToString(out); got != want {
+		t.Fatalf("XOF output does not match BLAKE2Xb known-answer vector:\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+// TestXOFChunkedRead checks that reading a XOF's output in many
+// small chunks yields the exact same bytes as reading it all at
+// once - i.e. the Read implementation does not depend on the
+// caller's buffer sizes.
+func TestXOFChunkedRead(t *testing.T) {
+	msg := []byte("BLAKE2X test message")
+
+	whole, err := NewXOF(&Params{XofLength: 256})
+	if err != nil {
+		t.Fatalf("Failed to create XOF: %s", err)
+	}
+	whole.Write(msg)
+	wholeOut := make([]byte, 256)
+	if _, err := io.ReadFull(whole, wholeOut); err != nil {
+		t.Fatalf("Failed to read from XOF: %s", err)
+	}
+
+	chunked, err := NewXOF(&Params{XofLength: 256})
+	if err != nil {
+		t.Fatalf("Failed to create XOF: %s", err)
+	}
+	chunked.Write(msg)
+	chunkedOut := make([]byte, 0, 256)
+	buf := make([]byte, 7)
+	for len(chunkedOut) < 256 {
+		n, err := chunked.Read(buf)
+		if err != nil {
+			t.Fatalf("Failed to read from XOF: %s", err)
+		}
+		chunkedOut = append(chunkedOut, buf[:n]...)
+	}
+
+	if !bytes.Equal(wholeOut, chunkedOut) {
+		t.Fatal("chunked XOF reads do not match a single bulk read")
+	}
+}
+
+func TestXOFUnboundedLength(t *testing.T) {
+	x, err := NewXOF(&Params{XofLength: UnknownXofLength})
+	if err != nil {
+		t.Fatalf("Failed to create unbounded XOF: %s", err)
+	}
+	x.Write([]byte("msg"))
+
+	out := make([]byte, 3*HashSize+1)
+	n, err := x.Read(out)
+	if err != nil {
+		t.Fatalf("Failed to read from unbounded XOF: %s", err)
+	}
+	if n != len(out) {
+		t.Fatalf("Expected to read %d bytes, got %d", len(out), n)
+	}
+}
+
+func TestNewXOFRejectsZeroLength(t *testing.T) {
+	if _, err := NewXOF(&Params{}); err == nil {
+		t.Fatal("NewXOF accepted a zero XofLength")
+	}
+}
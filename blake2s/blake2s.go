@@ -0,0 +1,287 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+// Package blake2s implements the BLAKE2s hash function
+// defined in RFC 7693. BLAKE2s produces digests of up to
+// 32 byte, supports keying (for use as a MAC), salting and
+// personalization, and - through the tree parameters added
+// to Params - tree hashing and the BLAKE2X extendable output
+// construction (see the blake2x.go file in this package).
+package blake2s
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash"
+)
+
+const (
+	// BlockSize is the block size of BLAKE2s in byte.
+	BlockSize = 64
+	// HashSize is the maximum size, in byte, of a BLAKE2s checksum.
+	HashSize = 32
+)
+
+var iv = [8]uint32{
+	0x6a09e667, 0xbb67ae85, 0x3c6ef372, 0xa54ff53a,
+	0x510e527f, 0x9b05688c, 0x1f83d9ab, 0x5be0cd19,
+}
+
+var sigma = [10][16]byte{
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	{14, 10, 4, 8, 9, 15, 13, 6, 1, 12, 0, 2, 11, 7, 5, 3},
+	{11, 8, 12, 0, 5, 2, 15, 13, 10, 14, 3, 6, 7, 1, 9, 4},
+	{7, 9, 3, 1, 13, 12, 11, 14, 2, 6, 5, 10, 4, 0, 15, 8},
+	{9, 0, 5, 7, 2, 4, 10, 15, 14, 1, 11, 12, 6, 8, 3, 13},
+	{2, 12, 6, 10, 0, 11, 8, 3, 4, 13, 7, 5, 15, 14, 1, 9},
+	{12, 5, 1, 15, 14, 13, 4, 10, 0, 7, 6, 3, 9, 2, 8, 11},
+	{13, 11, 7, 14, 12, 1, 3, 9, 5, 0, 15, 4, 8, 6, 2, 10},
+	{6, 15, 14, 9, 11, 3, 0, 8, 12, 2, 13, 7, 1, 4, 10, 5},
+	{10, 2, 8, 4, 7, 6, 1, 5, 15, 11, 9, 14, 3, 12, 13, 0},
+}
+
+// Params contains the BLAKE2s parameters. Besides the digest
+// size and an optional key, salt and personalization, Params
+// also carries the tree-hashing parameter block fields defined
+// by RFC 7693 section 2.10. A zero-valued Params describes
+// sequential (non-tree) hashing with the default digest size,
+// which is the behaviour most callers want.
+type Params struct {
+	HashSize int    // The hash size of BLAKE2s in bytes (default and max. is 32)
+	Key      []byte // The key for keyed hashing (up to 32 bytes)
+	Salt     []byte // The salt (up to 8 bytes)
+	Personal []byte // The personalization (up to 8 bytes)
+
+	// Tree hashing parameters. Leave these at their zero value
+	// for sequential hashing. See blake2x.go for how they are
+	// used to implement the BLAKE2X XOF construction.
+	Fanout     uint8  // The number of leaves in a level, 0 or 1 means sequential mode
+	Depth      uint8  // The maximal depth of the tree, 1 means sequential mode
+	LeafSize   uint32 // The maximal byte length of a leaf
+	NodeOffset uint64 // The node offset (the leaf or inner node's position in the tree)
+	NodeDepth  uint8  // The node depth (0 for leaves)
+	InnerSize  uint8  // The inner hash size used for chaining values (up to 32 bytes)
+
+	// XofLength is the BLAKE2X (see blake2x.go) output length in byte.
+	// It is 0 for regular, non-XOF hashing. UnknownXofLength marks a
+	// XOF of unbounded / not-yet-known output length.
+	XofLength uint32
+
+	// xofLeaf marks this Params as describing a BLAKE2X per-block leaf
+	// hash. Unlike every other zero-valued Fanout/Depth pair, which
+	// block() promotes to the sequential-mode default of 1, 1, a XOF
+	// leaf's Fanout and Depth must stay literally 0 as required by the
+	// BLAKE2X construction. Only nextBlock (blake2x.go) sets this.
+	xofLeaf bool
+}
+
+// UnknownXofLength marks a BLAKE2X output of unknown length, as
+// defined by the BLAKE2X specification.
+const UnknownXofLength = 1<<32 - 1
+
+// verifyParams checks whether p describes a valid parameter
+// set and returns a non-nil error otherwise.
+func verifyParams(p *Params) error {
+	if p.HashSize < 0 {
+		return errors.New("blake2s: invalid hash size")
+	}
+	if len(p.Key) > HashSize {
+		return errors.New("blake2s: invalid key size")
+	}
+	if len(p.Salt) > 8 {
+		return errors.New("blake2s: invalid salt size")
+	}
+	if len(p.Personal) > 8 {
+		return errors.New("blake2s: invalid personalization size")
+	}
+	if p.InnerSize > HashSize {
+		return errors.New("blake2s: invalid inner hash size")
+	}
+	if (p.Fanout > 1 || p.Depth > 1) && p.InnerSize == 0 {
+		return errors.New("blake2s: tree hashing requires a non-zero inner hash size")
+	}
+	return nil
+}
+
+// block builds the 32 byte BLAKE2s parameter block from p,
+// using hashSize as the (already clamped) digest length.
+func (p *Params) block(hashSize int) [32]byte {
+	var block [32]byte
+
+	block[0] = byte(hashSize)
+	block[1] = byte(len(p.Key))
+	fanout, depth := p.Fanout, p.Depth
+	if !p.xofLeaf && fanout == 0 && depth == 0 {
+		fanout, depth = 1, 1
+	}
+	block[2] = fanout
+	block[3] = depth
+	binary.LittleEndian.PutUint32(block[4:8], p.LeafSize)
+
+	// The node offset field is 6 byte wide (bytes 8-13). BLAKE2Xs
+	// overlays a 2 byte XOF output length on top of its high order 2
+	// bytes, as specified by the BLAKE2X construction.
+	binary.LittleEndian.PutUint32(block[8:12], uint32(p.NodeOffset))
+	binary.LittleEndian.PutUint16(block[12:14], uint16(p.XofLength))
+	block[14] = p.NodeDepth
+	block[15] = p.InnerSize
+	copy(block[16:24], p.Salt)
+	copy(block[24:32], p.Personal)
+	return block
+}
+
+// digest implements hash.Hash for BLAKE2s.
+type digest struct {
+	hashSize int
+	h        [8]uint32
+	t0, t1   uint32
+	f0       uint32
+
+	buf    [BlockSize]byte
+	buflen int
+
+	key []byte
+}
+
+// New returns a new hash.Hash computing the BLAKE2s checksum
+// with the given parameters. A nil Params is equivalent to
+// new(Params), i.e. an unkeyed 32 byte digest.
+func New(p *Params) (hash.Hash, error) {
+	if p == nil {
+		p = new(Params)
+	}
+	if err := verifyParams(p); err != nil {
+		return nil, err
+	}
+
+	hashSize := p.HashSize
+	if hashSize <= 0 || hashSize > HashSize {
+		hashSize = HashSize
+	}
+
+	d := &digest{hashSize: hashSize}
+	block := p.block(hashSize)
+
+	d.h = iv
+	for i := range d.h {
+		d.h[i] ^= binary.LittleEndian.Uint32(block[i*4 : i*4+4])
+	}
+
+	if len(p.Key) > 0 {
+		d.key = make([]byte, BlockSize)
+		copy(d.key, p.Key)
+		d.Write(d.key)
+	}
+	return d, nil
+}
+
+// Sum returns the BLAKE2s checksum of msg using the given
+// parameters.
+func Sum(msg []byte, p *Params) ([]byte, error) {
+	h, err := New(p)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(msg)
+	return h.Sum(nil), nil
+}
+
+func (d *digest) BlockSize() int { return BlockSize }
+
+func (d *digest) Size() int { return d.hashSize }
+
+func (d *digest) Reset() {
+	panic("blake2s: Reset is not supported - create a new hash instead")
+}
+
+func (d *digest) Write(p []byte) (n int, err error) {
+	n = len(p)
+	if d.buflen > 0 {
+		free := BlockSize - d.buflen
+		if free > len(p) {
+			free = len(p)
+		}
+		copy(d.buf[d.buflen:], p[:free])
+		d.buflen += free
+		p = p[free:]
+		if len(p) == 0 {
+			return
+		}
+		d.addLength(BlockSize)
+		compress(d, d.buf[:], 0)
+		d.buflen = 0
+	}
+	for len(p) > BlockSize {
+		d.addLength(BlockSize)
+		compress(d, p[:BlockSize], 0)
+		p = p[BlockSize:]
+	}
+	d.buflen = copy(d.buf[:], p)
+	return
+}
+
+func (d *digest) addLength(n uint32) {
+	d.t0 += n
+	if d.t0 < n {
+		d.t1++
+	}
+}
+
+func (d *digest) Sum(b []byte) []byte {
+	cpy := *d
+	cpy.addLength(uint32(cpy.buflen))
+	for i := cpy.buflen; i < BlockSize; i++ {
+		cpy.buf[i] = 0
+	}
+	cpy.f0 = ^uint32(0)
+	compress(&cpy, cpy.buf[:], cpy.f0)
+
+	var out [HashSize]byte
+	for i, v := range cpy.h {
+		binary.LittleEndian.PutUint32(out[i*4:i*4+4], v)
+	}
+	return append(b, out[:cpy.hashSize]...)
+}
+
+func rotr32(x uint32, n uint) uint32 { return (x >> n) | (x << (32 - n)) }
+
+// compress absorbs one 64 byte message block into d, updating
+// the internal counter by t (already added via addLength by the
+// caller) and finalizing the state if f is the all-ones mask.
+func compress(d *digest, block []byte, f uint32) {
+	var m [16]uint32
+	for i := range m {
+		m[i] = binary.LittleEndian.Uint32(block[i*4 : i*4+4])
+	}
+
+	v := [16]uint32{
+		d.h[0], d.h[1], d.h[2], d.h[3], d.h[4], d.h[5], d.h[6], d.h[7],
+		iv[0], iv[1], iv[2], iv[3], iv[4] ^ d.t0, iv[5] ^ d.t1, iv[6] ^ f, iv[7],
+	}
+
+	g := func(a, b, c, d2 int, x, y uint32) {
+		v[a] += v[b] + x
+		v[d2] = rotr32(v[d2]^v[a], 16)
+		v[c] += v[d2]
+		v[b] = rotr32(v[b]^v[c], 12)
+		v[a] += v[b] + y
+		v[d2] = rotr32(v[d2]^v[a], 8)
+		v[c] += v[d2]
+		v[b] = rotr32(v[b]^v[c], 7)
+	}
+
+	for _, s := range sigma {
+		g(0, 4, 8, 12, m[s[0]], m[s[1]])
+		g(1, 5, 9, 13, m[s[2]], m[s[3]])
+		g(2, 6, 10, 14, m[s[4]], m[s[5]])
+		g(3, 7, 11, 15, m[s[6]], m[s[7]])
+		g(0, 5, 10, 15, m[s[8]], m[s[9]])
+		g(1, 6, 11, 12, m[s[10]], m[s[11]])
+		g(2, 7, 8, 13, m[s[12]], m[s[13]])
+		g(3, 4, 9, 14, m[s[14]], m[s[15]])
+	}
+
+	for i := 0; i < 8; i++ {
+		d.h[i] ^= v[i] ^ v[i+8]
+	}
+}
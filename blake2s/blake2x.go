@@ -0,0 +1,147 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package blake2s
+
+import (
+	"errors"
+	"io"
+)
+
+// XOF defines the interface of a BLAKE2X extendable output
+// function. A XOF behaves like a hash.Hash that absorbs an
+// arbitrary amount of input via Write and then, instead of a
+// fixed-size checksum, can be read from to obtain an arbitrary
+// number of pseudo-random output bytes.
+type XOF interface {
+	io.Writer
+	io.Reader
+
+	// Size returns the number of bytes the XOF will yield, or
+	// UnknownXofLength if the XOF was created with an unknown
+	// output length.
+	Size() uint32
+}
+
+// NewXOF returns a new BLAKE2X extendable output function (XOF)
+// using the given parameters. params.XofLength determines the
+// number of bytes the XOF will produce - it must either be
+// UnknownXofLength or a value greater than zero and less than
+// 0xFFFF. The root hash
+// size (params.HashSize) defaults to HashSize and determines the
+// size of the intermediate, per-block BLAKE2s invocations - it
+// does not bound the total XOF output length.
+//
+// The root hash H0 is derived from params and the bytes written
+// to the returned XOF. Reading from the XOF then generates the
+// output stream block by block as described by the BLAKE2X
+// specification: the i-th block is BLAKE2s(H0) run with
+// fanout = depth = 0, node_offset = i, node_depth = 0,
+// leaf_length = len(H0), inner_length = HashSize and
+// xof_length = params.XofLength.
+func NewXOF(params *Params) (XOF, error) {
+	if params == nil {
+		params = new(Params)
+	}
+	if params.XofLength == 0 {
+		return nil, errors.New("blake2s: XofLength must be UnknownXofLength or greater than 0")
+	}
+	if params.XofLength != UnknownXofLength && params.XofLength >= 0xFFFF {
+		// 0xFFFF itself is reserved by the BLAKE2Xs parameter block to
+		// mean "unknown length", so it cannot also be a valid finite one.
+		return nil, errors.New("blake2s: XofLength must fit in the 16 bit BLAKE2Xs xof_length field")
+	}
+
+	p := *params
+	root, err := New(&p)
+	if err != nil {
+		return nil, err
+	}
+
+	rootHashSize := p.HashSize
+	if rootHashSize <= 0 || rootHashSize > HashSize {
+		rootHashSize = HashSize
+	}
+	return &xof{root: root.(*digest), length: params.XofLength, rootHashSize: rootHashSize}, nil
+}
+
+type xof struct {
+	root         *digest
+	rootHashSize int
+
+	h0        []byte
+	finalized bool
+
+	block    []byte
+	blockOff int
+	counter  uint32
+	length   uint32
+}
+
+func (x *xof) Size() uint32 { return x.length }
+
+func (x *xof) Write(p []byte) (n int, err error) {
+	if x.finalized {
+		return 0, errors.New("blake2s: XOF already finalized, cannot write more data")
+	}
+	return x.root.Write(p)
+}
+
+func (x *xof) finalize() {
+	x.h0 = x.root.Sum(nil)
+	x.finalized = true
+}
+
+func (x *xof) nextBlock() error {
+	if x.length != UnknownXofLength && uint64(x.counter)*uint64(HashSize) >= uint64(x.length) {
+		return io.EOF
+	}
+	if x.counter == UnknownXofLength {
+		return errors.New("blake2s: BLAKE2X output counter overflow")
+	}
+
+	outSize := HashSize
+	if x.length != UnknownXofLength {
+		remaining := uint64(x.length) - uint64(x.counter)*uint64(HashSize)
+		if remaining < uint64(HashSize) {
+			outSize = int(remaining)
+		}
+	}
+
+	p := Params{
+		HashSize:   outSize,
+		LeafSize:   uint32(x.rootHashSize),
+		NodeOffset: uint64(x.counter),
+		NodeDepth:  0,
+		InnerSize:  HashSize,
+		XofLength:  x.length,
+		xofLeaf:    true,
+	}
+	block, err := Sum(x.h0, &p)
+	if err != nil {
+		return err
+	}
+	x.block, x.blockOff = block, 0
+	x.counter++
+	return nil
+}
+
+func (x *xof) Read(p []byte) (n int, err error) {
+	if !x.finalized {
+		x.finalize()
+	}
+	for n < len(p) {
+		if x.blockOff == len(x.block) {
+			if err = x.nextBlock(); err != nil {
+				if err == io.EOF && n > 0 {
+					return n, nil
+				}
+				return n, err
+			}
+		}
+		c := copy(p[n:], x.block[x.blockOff:])
+		n += c
+		x.blockOff += c
+	}
+	return n, nil
+}
@@ -0,0 +1,101 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package blake2s
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"testing"
+)
+
+// TestXOFKnownAnswer checks NewXOF's output against a vector produced
+// by golang.org/x/crypto/blake2s's BLAKE2Xs implementation, to catch
+// any deviation from the BLAKE2X reference construction that a purely
+// internal round-trip test (like TestXOFChunkedRead) cannot.
+func TestXOFKnownAnswer(t *testing.T) {
+	const want = "39eebe107b7e05f1ea21870811e332f5cf913ca6b120d1807d8ca2fb719892ba" +
+		"7af1f3ec59a5c9ebcdf12606188b57d09a0b3214e9f0f4cbba086b2c8c357ee0" +
+		"17be90cb900ca3249df73d20c559cf8f26593d598eb3caa303626a69e643de2" +
+		"d9ff7a3b79ccbcdb27e11b66efe973a08a6753edb2b3ac478c8ac81e5ec79d9c" +
+		"62ffd4357011bfe23a15b82069bee53eb1a25ee647189d66cc7fa324e49186dd" +
+		"c95171f14aade8b947fdb67b641313ee18efcce30ddab014911d120024c6ced2" +
+		"03e3ae3a5ae3f9b57b3a1fb7bba1020e41b34ee5e6cf0404380d0f09f802068c" +
+		"e7bfd41fa38e9534a780e95434218500a380f3c3a4a5983c47e17ed1735a85be" +
+		"6"
+
+	x, err := NewXOF(&Params{XofLength: 256})
+	if err != nil {
+		t.Fatalf("Failed to create XOF: %s", err)
+	}
+	x.Write([]byte("abc"))
+
+	out := make([]byte, 256)
+	if _, err := io.ReadFull(x, out); err != nil {
+		t.Fatalf("Failed to read from XOF: %s", err)
+	}
+	if got := hex.EncodeToString(out); got != want {
+		t.Fatalf("XOF output does not match BLAKE2Xs known-answer vector:\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+// TestXOFChunkedRead checks that reading a XOF's output in many
+// small chunks yields the exact same bytes as reading it all at
+// once - i.e. the Read implementation does not depend on the
+// caller's buffer sizes.
+func TestXOFChunkedRead(t *testing.T) {
+	msg := []byte("BLAKE2X test message")
+
+	whole, err := NewXOF(&Params{XofLength: 128})
+	if err != nil {
+		t.Fatalf("Failed to create XOF: %s", err)
+	}
+	whole.Write(msg)
+	wholeOut := make([]byte, 128)
+	if _, err := io.ReadFull(whole, wholeOut); err != nil {
+		t.Fatalf("Failed to read from XOF: %s", err)
+	}
+
+	chunked, err := NewXOF(&Params{XofLength: 128})
+	if err != nil {
+		t.Fatalf("Failed to create XOF: %s", err)
+	}
+	chunked.Write(msg)
+	chunkedOut := make([]byte, 0, 128)
+	buf := make([]byte, 5)
+	for len(chunkedOut) < 128 {
+		n, err := chunked.Read(buf)
+		if err != nil {
+			t.Fatalf("Failed to read from XOF: %s", err)
+		}
+		chunkedOut = append(chunkedOut, buf[:n]...)
+	}
+
+	if !bytes.Equal(wholeOut, chunkedOut) {
+		t.Fatal("chunked XOF reads do not match a single bulk read")
+	}
+}
+
+func TestXOFUnboundedLength(t *testing.T) {
+	x, err := NewXOF(&Params{XofLength: UnknownXofLength})
+	if err != nil {
+		t.Fatalf("Failed to create unbounded XOF: %s", err)
+	}
+	x.Write([]byte("msg"))
+
+	out := make([]byte, 3*HashSize+1)
+	n, err := x.Read(out)
+	if err != nil {
+		t.Fatalf("Failed to read from unbounded XOF: %s", err)
+	}
+	if n != len(out) {
+		t.Fatalf("Expected to read %d bytes, got %d", len(out), n)
+	}
+}
+
+func TestNewXOFRejectsZeroLength(t *testing.T) {
+	if _, err := NewXOF(&Params{}); err == nil {
+		t.Fatal("NewXOF accepted a zero XofLength")
+	}
+}
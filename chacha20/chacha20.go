@@ -0,0 +1,279 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+// Package chacha20 implements the ChaCha stream cipher and
+// the ChaCha20Poly1305 AEAD construction described in RFC 7539.
+//
+// ChaCha20 uses a 32 bit counter and produces 64 byte keystream per
+// iteration. Following ChaCha20 can en/decrypt up to 2^32 * 64 byte
+// for one key-nonce combination. Notice that one specific key-nonce
+// combination must be unique for all time.
+package chacha20
+
+import (
+	"crypto/cipher"
+
+	"github.com/EncEve/crypto"
+)
+
+// NonceSize is the size of the ChaCha20 nonce in bytes.
+const NonceSize = 12
+
+const rounds = 20
+
+var constants = [16]byte{
+	0x65, 0x78, 0x70, 0x61,
+	0x6e, 0x64, 0x20, 0x33,
+	0x32, 0x2d, 0x62, 0x79,
+	0x74, 0x65, 0x20, 0x6b,
+}
+
+// chaCha20 is the ChaCha20 cipher.Stream implementation.
+type chaCha20 struct {
+	state, block [64]byte
+	off          int
+}
+
+// NewCipher returns a new cipher.Stream implementing the ChaCha20
+// stream cipher. The nonce must be unique for one key for all time.
+func NewCipher(nonce *[NonceSize]byte, key *[32]byte) cipher.Stream {
+	c := new(chaCha20)
+	copy(c.state[:], constants[:])
+	copy(c.state[16:], key[:])
+	copy(c.state[52:], nonce[:])
+	return c
+}
+
+// SetCounter sets the counter of the cipher. Notice that this
+// function skips the unused keystream of the current 64 byte block.
+func (c *chaCha20) SetCounter(ctr uint32) {
+	c.state[48] = byte(ctr)
+	c.state[49] = byte(ctr >> 8)
+	c.state[50] = byte(ctr >> 16)
+	c.state[51] = byte(ctr >> 24)
+	c.off = 0
+}
+
+// XORKeyStream crypts bytes from src to dst. Src and dst may be
+// the same slice but otherwise should not overlap. If len(dst) <
+// len(src) the function panics.
+func (c *chaCha20) XORKeyStream(dst, src []byte) {
+	length := len(src)
+	if len(dst) < length {
+		panic("chacha20: dst buffer is to small")
+	}
+
+	if c.off > 0 {
+		n := crypto.XOR(dst, src, c.block[c.off:])
+		if n == length {
+			c.off += n
+			return
+		}
+		src = src[n:]
+		dst = dst[n:]
+		length -= n
+		c.off = 0
+	}
+
+	if n := length & (^(64 - 1)); n > 0 {
+		xorBlocks(dst, src, &c.state, n)
+	}
+
+	if n := length & (^(64 - 1)); length-n > 0 {
+		core(&c.block, &c.state)
+		c.off += crypto.XOR(dst[n:], src[n:], c.block[:])
+	}
+}
+
+// XORKeyStream crypts bytes from src to dst using the given key,
+// nonce and counter. Src and dst may be the same slice but otherwise
+// should not overlap. If len(dst) < len(src) this function panics.
+func XORKeyStream(dst, src []byte, nonce *[NonceSize]byte, key *[32]byte, counter uint32) {
+	length := len(src)
+	if len(dst) < length {
+		panic("chacha20: dst buffer is to small")
+	}
+
+	var state [64]byte
+	copy(state[:], constants[:])
+	copy(state[16:], key[:])
+	state[48] = byte(counter)
+	state[49] = byte(counter >> 8)
+	state[50] = byte(counter >> 16)
+	state[51] = byte(counter >> 24)
+	copy(state[52:], nonce[:])
+
+	if n := length & (^(64 - 1)); n > 0 {
+		xorBlocks(dst, src, &state, n)
+	}
+
+	if n := length & (^(64 - 1)); length-n > 0 {
+		var block [64]byte
+		core(&block, &state)
+		crypto.XOR(dst[n:], src[n:], block[:])
+	}
+}
+
+// xorBlocks crypts n (a multiple of 64) bytes from src to dst using
+// state, incrementing its counter once per 64 byte block.
+func xorBlocks(dst, src []byte, state *[64]byte, n int) {
+	var block [64]byte
+	for i := 0; i < n; i += 64 {
+		core(&block, state)
+		crypto.XOR(dst[i:], src[i:], block[:])
+	}
+}
+
+// core generates one 64 byte keystream block from state running
+// the ChaCha20 round function and writes it to dst. It increments
+// the counter embedded in state.
+func core(dst, state *[64]byte) {
+	v00 := uint32(state[0]) | (uint32(state[1]) << 8) | (uint32(state[2]) << 16) | (uint32(state[3]) << 24)
+	v01 := uint32(state[4]) | (uint32(state[5]) << 8) | (uint32(state[6]) << 16) | (uint32(state[7]) << 24)
+	v02 := uint32(state[8]) | (uint32(state[9]) << 8) | (uint32(state[10]) << 16) | (uint32(state[11]) << 24)
+	v03 := uint32(state[12]) | (uint32(state[13]) << 8) | (uint32(state[14]) << 16) | (uint32(state[15]) << 24)
+	v04 := uint32(state[16]) | (uint32(state[17]) << 8) | (uint32(state[18]) << 16) | (uint32(state[19]) << 24)
+	v05 := uint32(state[20]) | (uint32(state[21]) << 8) | (uint32(state[22]) << 16) | (uint32(state[23]) << 24)
+	v06 := uint32(state[24]) | (uint32(state[25]) << 8) | (uint32(state[26]) << 16) | (uint32(state[27]) << 24)
+	v07 := uint32(state[28]) | (uint32(state[29]) << 8) | (uint32(state[30]) << 16) | (uint32(state[31]) << 24)
+	v08 := uint32(state[32]) | (uint32(state[33]) << 8) | (uint32(state[34]) << 16) | (uint32(state[35]) << 24)
+	v09 := uint32(state[36]) | (uint32(state[37]) << 8) | (uint32(state[38]) << 16) | (uint32(state[39]) << 24)
+	v10 := uint32(state[40]) | (uint32(state[41]) << 8) | (uint32(state[42]) << 16) | (uint32(state[43]) << 24)
+	v11 := uint32(state[44]) | (uint32(state[45]) << 8) | (uint32(state[46]) << 16) | (uint32(state[47]) << 24)
+	v12 := uint32(state[48]) | (uint32(state[49]) << 8) | (uint32(state[50]) << 16) | (uint32(state[51]) << 24)
+	v13 := uint32(state[52]) | (uint32(state[53]) << 8) | (uint32(state[54]) << 16) | (uint32(state[55]) << 24)
+	v14 := uint32(state[56]) | (uint32(state[57]) << 8) | (uint32(state[58]) << 16) | (uint32(state[59]) << 24)
+	v15 := uint32(state[60]) | (uint32(state[61]) << 8) | (uint32(state[62]) << 16) | (uint32(state[63]) << 24)
+
+	s00, s01, s02, s03, s04, s05, s06, s07 := v00, v01, v02, v03, v04, v05, v06, v07
+	s08, s09, s10, s11, s12, s13, s14, s15 := v08, v09, v10, v11, v12, v13, v14, v15
+
+	for i := 0; i < rounds; i += 2 {
+		v00 += v04
+		v12 ^= v00
+		v12 = (v12 << 16) | (v12 >> 16)
+		v08 += v12
+		v04 ^= v08
+		v04 = (v04 << 12) | (v04 >> 20)
+		v00 += v04
+		v12 ^= v00
+		v12 = (v12 << 8) | (v12 >> 24)
+		v08 += v12
+		v04 ^= v08
+		v04 = (v04 << 7) | (v04 >> 25)
+		v01 += v05
+		v13 ^= v01
+		v13 = (v13 << 16) | (v13 >> 16)
+		v09 += v13
+		v05 ^= v09
+		v05 = (v05 << 12) | (v05 >> 20)
+		v01 += v05
+		v13 ^= v01
+		v13 = (v13 << 8) | (v13 >> 24)
+		v09 += v13
+		v05 ^= v09
+		v05 = (v05 << 7) | (v05 >> 25)
+		v02 += v06
+		v14 ^= v02
+		v14 = (v14 << 16) | (v14 >> 16)
+		v10 += v14
+		v06 ^= v10
+		v06 = (v06 << 12) | (v06 >> 20)
+		v02 += v06
+		v14 ^= v02
+		v14 = (v14 << 8) | (v14 >> 24)
+		v10 += v14
+		v06 ^= v10
+		v06 = (v06 << 7) | (v06 >> 25)
+		v03 += v07
+		v15 ^= v03
+		v15 = (v15 << 16) | (v15 >> 16)
+		v11 += v15
+		v07 ^= v11
+		v07 = (v07 << 12) | (v07 >> 20)
+		v03 += v07
+		v15 ^= v03
+		v15 = (v15 << 8) | (v15 >> 24)
+		v11 += v15
+		v07 ^= v11
+		v07 = (v07 << 7) | (v07 >> 25)
+		v00 += v05
+		v15 ^= v00
+		v15 = (v15 << 16) | (v15 >> 16)
+		v10 += v15
+		v05 ^= v10
+		v05 = (v05 << 12) | (v05 >> 20)
+		v00 += v05
+		v15 ^= v00
+		v15 = (v15 << 8) | (v15 >> 24)
+		v10 += v15
+		v05 ^= v10
+		v05 = (v05 << 7) | (v05 >> 25)
+		v01 += v06
+		v12 ^= v01
+		v12 = (v12 << 16) | (v12 >> 16)
+		v11 += v12
+		v06 ^= v11
+		v06 = (v06 << 12) | (v06 >> 20)
+		v01 += v06
+		v12 ^= v01
+		v12 = (v12 << 8) | (v12 >> 24)
+		v11 += v12
+		v06 ^= v11
+		v06 = (v06 << 7) | (v06 >> 25)
+		v02 += v07
+		v13 ^= v02
+		v13 = (v13 << 16) | (v13 >> 16)
+		v08 += v13
+		v07 ^= v08
+		v07 = (v07 << 12) | (v07 >> 20)
+		v02 += v07
+		v13 ^= v02
+		v13 = (v13 << 8) | (v13 >> 24)
+		v08 += v13
+		v07 ^= v08
+		v07 = (v07 << 7) | (v07 >> 25)
+		v03 += v04
+		v14 ^= v03
+		v14 = (v14 << 16) | (v14 >> 16)
+		v09 += v14
+		v04 ^= v09
+		v04 = (v04 << 12) | (v04 >> 20)
+		v03 += v04
+		v14 ^= v03
+		v14 = (v14 << 8) | (v14 >> 24)
+		v09 += v14
+		v04 ^= v09
+		v04 = (v04 << 7) | (v04 >> 25)
+	}
+
+	v00 += s00
+	v01 += s01
+	v02 += s02
+	v03 += s03
+	v04 += s04
+	v05 += s05
+	v06 += s06
+	v07 += s07
+	v08 += s08
+	v09 += s09
+	v10 += s10
+	v11 += s11
+	v12 += s12
+	v13 += s13
+	v14 += s14
+	v15 += s15
+
+	s12++
+	state[48] = byte(s12)
+	state[49] = byte(s12 >> 8)
+	state[50] = byte(s12 >> 16)
+	state[51] = byte(s12 >> 24)
+
+	for i, v := range [16]uint32{v00, v01, v02, v03, v04, v05, v06, v07, v08, v09, v10, v11, v12, v13, v14, v15} {
+		dst[i*4] = byte(v)
+		dst[i*4+1] = byte(v >> 8)
+		dst[i*4+2] = byte(v >> 16)
+		dst[i*4+3] = byte(v >> 24)
+	}
+}
@@ -0,0 +1,122 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package chacha20
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+
+	"github.com/EncEve/crypto"
+)
+
+// HKDFNonceSize is the size of the nonce accepted by the AEAD
+// instances returned from NewHKDFChaCha20Poly1305 and
+// NewHKDFChaCha20Poly1305WithTagSize.
+const HKDFNonceSize = 24
+
+// hkdfInfo is the fixed HKDF info string used to domain-separate
+// the sub-key/sub-nonce derivation from any other use of HKDF-SHA256
+// with the same key.
+var hkdfInfo = []byte("EncEve/crypto chacha20poly1305 hkdf nonce extension")
+
+// NewHKDFChaCha20Poly1305 returns a cipher.AEAD that accepts
+// HKDFNonceSize (24) byte nonces. Unlike plain ChaCha20Poly1305,
+// whose 12 byte nonce is too short to pick at random, this
+// construction derives a fresh ChaCha20Poly1305 sub-key and
+// sub-nonce from the 32 byte key and the 24 byte nonce via
+// HKDF-SHA256 before every Seal/Open, so callers can use random
+// 24 byte nonces without a birthday-bound collision risk.
+//
+// The first 16 byte of the nonce are used as the HKDF salt; the
+// remaining 8 byte are mixed (XORed) into the derived ChaCha20
+// nonce as an additional, cheap safety margin.
+func NewHKDFChaCha20Poly1305(key *[32]byte) cipher.AEAD {
+	c, _ := NewHKDFChaCha20Poly1305WithTagSize(key, TagSize)
+	return c
+}
+
+// NewHKDFChaCha20Poly1305WithTagSize is like NewHKDFChaCha20Poly1305
+// but truncates the Poly1305 tag to tagsize byte. The tagsize must
+// be between 1 and TagSize.
+func NewHKDFChaCha20Poly1305WithTagSize(key *[32]byte, tagsize int) (cipher.AEAD, error) {
+	if tagsize < 1 || tagsize > TagSize {
+		return nil, errors.New("chacha20: tag size must be between 1 and 16")
+	}
+	c := &hkdfAEAD{tagsize: tagsize}
+	copy(c.key[:], key[:])
+	return c, nil
+}
+
+// hkdfAEAD wraps the plain ChaCha20Poly1305 AEAD with a per-message
+// HKDF-SHA256 sub-key/sub-nonce derivation.
+type hkdfAEAD struct {
+	key     [32]byte
+	tagsize int
+}
+
+func (c *hkdfAEAD) NonceSize() int { return HKDFNonceSize }
+
+func (c *hkdfAEAD) Overhead() int { return c.tagsize }
+
+// derive computes the per-message ChaCha20Poly1305 sub-key and
+// sub-nonce from c.key and the given HKDFNonceSize byte nonce.
+func (c *hkdfAEAD) derive(nonce []byte) (subkey [32]byte, subnonce [12]byte) {
+	prk := hkdfExtract(nonce[:16], c.key[:])
+	okm := hkdfExpand(prk, hkdfInfo, 32+12)
+	copy(subkey[:], okm[:32])
+	copy(subnonce[:], okm[32:44])
+	for i := 0; i < 8; i++ {
+		subnonce[i] ^= nonce[16+i]
+	}
+	return
+}
+
+func (c *hkdfAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if n := len(nonce); n != HKDFNonceSize {
+		panic(crypto.NonceSizeError(n))
+	}
+	subkey, subnonce := c.derive(nonce)
+	inner, _ := NewChaCha20Poly1305WithTagSize(&subkey, c.tagsize)
+	return inner.Seal(dst, subnonce[:], plaintext, additionalData)
+}
+
+func (c *hkdfAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if n := len(nonce); n != HKDFNonceSize {
+		return nil, crypto.NonceSizeError(n)
+	}
+	subkey, subnonce := c.derive(nonce)
+	inner, err := NewChaCha20Poly1305WithTagSize(&subkey, c.tagsize)
+	if err != nil {
+		return nil, err
+	}
+	return inner.Open(dst, subnonce[:], ciphertext, additionalData)
+}
+
+// hkdfExtract implements the HKDF-Extract step (RFC 5869) using
+// HMAC-SHA256.
+func hkdfExtract(salt, ikm []byte) []byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(ikm)
+	return mac.Sum(nil)
+}
+
+// hkdfExpand implements the HKDF-Expand step (RFC 5869) using
+// HMAC-SHA256, returning length byte of output key material.
+func hkdfExpand(prk, info []byte, length int) []byte {
+	var (
+		t   []byte
+		okm = make([]byte, 0, length)
+	)
+	for i := byte(1); len(okm) < length; i++ {
+		mac := hmac.New(sha256.New, prk)
+		mac.Write(t)
+		mac.Write(info)
+		mac.Write([]byte{i})
+		t = mac.Sum(nil)
+		okm = append(okm, t...)
+	}
+	return okm[:length]
+}
@@ -0,0 +1,130 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package chacha20
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestHKDFNonceSize(t *testing.T) {
+	var key [32]byte
+	c := NewHKDFChaCha20Poly1305(&key)
+	if n := c.NonceSize(); n != HKDFNonceSize {
+		t.Fatalf("Expected %d but NonceSize() returned %d", HKDFNonceSize, n)
+	}
+}
+
+func TestHKDFNewWithTagSize(t *testing.T) {
+	var key [32]byte
+	if _, err := NewHKDFChaCha20Poly1305WithTagSize(&key, 0); err == nil {
+		t.Fatal("NewHKDFChaCha20Poly1305WithTagSize accepted invalid tag size: 0")
+	}
+	if _, err := NewHKDFChaCha20Poly1305WithTagSize(&key, 17); err == nil {
+		t.Fatal("NewHKDFChaCha20Poly1305WithTagSize accepted invalid tag size: 17")
+	}
+}
+
+func TestHKDFSealOpen(t *testing.T) {
+	var key [32]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	c := NewHKDFChaCha20Poly1305(&key)
+
+	nonce := make([]byte, HKDFNonceSize)
+	for i := range nonce {
+		nonce[i] = byte(255 - i)
+	}
+	msg := []byte("the quick brown fox jumps over the lazy dog")
+	ad := []byte("additional data")
+
+	ciphertext := c.Seal(make([]byte, len(msg)+c.Overhead()), nonce, msg, ad)
+	plaintext, err := c.Open(make([]byte, len(msg)), nonce, ciphertext, ad)
+	if err != nil {
+		t.Fatalf("Failed to open ciphertext: %s", err)
+	}
+	if !bytes.Equal(plaintext, msg) {
+		t.Fatal("decrypted plaintext does not match original message")
+	}
+}
+
+func TestHKDFDistinctNoncesDeriveDistinctSubkeys(t *testing.T) {
+	var key [32]byte
+	c := NewHKDFChaCha20Poly1305(&key).(*hkdfAEAD)
+
+	nonceA := make([]byte, HKDFNonceSize)
+	nonceB := make([]byte, HKDFNonceSize)
+	nonceB[0] = 1
+
+	subkeyA, subnonceA := c.derive(nonceA)
+	subkeyB, subnonceB := c.derive(nonceB)
+
+	if subkeyA == subkeyB && subnonceA == subnonceB {
+		t.Fatal("two distinct nonces produced the same sub-key and sub-nonce")
+	}
+}
+
+func TestHKDFRejectsWrongNonceSize(t *testing.T) {
+	var key [32]byte
+	c := NewHKDFChaCha20Poly1305(&key)
+
+	nonce := make([]byte, HKDFNonceSize-1)
+	if _, err := c.Open(nil, nonce, make([]byte, TagSize), nil); err == nil {
+		t.Fatal("Open() accepted invalid nonce size")
+	}
+}
+
+// Known-answer vector for the HKDF sub-key/sub-nonce derivation and
+// the resulting Seal output, computed independently of this package:
+// the HKDF-Extract/Expand steps were run through a standalone RFC
+// 5869 implementation, and the ChaCha20-Poly1305 Seal was computed
+// over the derived sub-key/sub-nonce with golang.org/x/crypto's
+// chacha20poly1305. Pinning both the intermediate derive() output and
+// the final ciphertext catches a regression in the salt/info/XOR
+// layout even if it happens to leave round-tripping intact.
+func TestHKDFKnownAnswerVector(t *testing.T) {
+	key := fromHex("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f")
+	nonce := fromHex("fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0efeeedecebeae9e8")
+	msg := []byte("the quick brown fox jumps over the lazy dog")
+	ad := []byte("additional data")
+
+	wantSubkey := fromHex("2eeb975660cbc8eb030e2e5216ee6bfeddf0ce8d7fd325f7a5ae7f730acad0fa")
+	wantSubnonce := fromHex("d1c7d7834cad9df7afca1c61")
+	wantCiphertext := fromHex("2af0936c2044f1a906954ea1650e9d3f4733596b884ec1e5b913a4efbebeb75" +
+		"57705966d1b0662a627aac27fa357270ca75703829986128ac12f50")
+
+	var Key [32]byte
+	copy(Key[:], key)
+	c := NewHKDFChaCha20Poly1305(&Key).(*hkdfAEAD)
+
+	subkey, subnonce := c.derive(nonce)
+	if !bytes.Equal(subkey[:], wantSubkey) {
+		t.Fatalf("derive() subkey:\nFound   : %s\nExpected: %s", hex.EncodeToString(subkey[:]), hex.EncodeToString(wantSubkey))
+	}
+	if !bytes.Equal(subnonce[:], wantSubnonce) {
+		t.Fatalf("derive() subnonce:\nFound   : %s\nExpected: %s", hex.EncodeToString(subnonce[:]), hex.EncodeToString(wantSubnonce))
+	}
+
+	ciphertext := c.Seal(make([]byte, len(msg)+c.Overhead()), nonce, msg, ad)
+	if !bytes.Equal(ciphertext, wantCiphertext) {
+		t.Fatalf("Seal():\nFound   : %s\nExpected: %s", hex.EncodeToString(ciphertext), hex.EncodeToString(wantCiphertext))
+	}
+}
+
+func TestHKDFTamperedCiphertextFailsToOpen(t *testing.T) {
+	var key [32]byte
+	c := NewHKDFChaCha20Poly1305(&key)
+
+	nonce := make([]byte, HKDFNonceSize)
+	msg := []byte("secret message")
+
+	ciphertext := c.Seal(make([]byte, len(msg)+c.Overhead()), nonce, msg, nil)
+	ciphertext[0] ^= 1
+
+	if _, err := c.Open(make([]byte, len(msg)), nonce, ciphertext, nil); err == nil {
+		t.Fatal("Open() accepted a tampered ciphertext")
+	}
+}
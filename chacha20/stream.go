@@ -0,0 +1,131 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package chacha20
+
+import (
+	"crypto/cipher"
+	"errors"
+)
+
+// NoncePrefixSize is the size, in byte, of the nonce prefix accepted
+// by NewSTREAM.
+const NoncePrefixSize = NonceSize - 5
+
+// maxStreamCounter is the largest chunk counter STREAM will use - the
+// counter must leave room for the trailing last-chunk byte within
+// the 12 byte ChaCha20Poly1305 nonce.
+const maxStreamCounter = 1<<32 - 1
+
+// ErrStreamCounterOverflow is returned by OpenChunk for a non-final
+// chunk once a STREAM has processed maxStreamCounter non-final chunks
+// and cannot safely continue - the per-chunk nonce counter would
+// otherwise repeat. A final chunk is still accepted at that point. SealChunk panics in the same situation, mirroring how the
+// underlying AEAD signals misuse on the encrypting side.
+var ErrStreamCounterOverflow = errors.New("chacha20: STREAM chunk counter overflow")
+
+// errStreamFinished is used internally once a STREAM has sealed or
+// opened its final chunk and must not be used again.
+var errStreamFinished = errors.New("chacha20: STREAM already finished")
+
+// STREAM implements the nonce-based online AEAD construction of
+// Hoang, Reyhanitabar, Rogaway and Vizár ("Online Authenticated-
+// Encryption and its Nonce-Reuse Misuse-Resistance", CRYPTO 2015)
+// on top of ChaCha20Poly1305: a large plaintext is split into chunks,
+// each sealed with its own nonce derived from a fixed prefix, a
+// monotonically increasing counter and a flag marking the final
+// chunk. Binding the final-chunk flag into the nonce (and therefore
+// into the auth. tag of every chunk) prevents an attacker from
+// truncating a message by dropping its last chunks.
+type STREAM struct {
+	aead    cipher.AEAD
+	prefix  [NoncePrefixSize]byte
+	counter uint32
+	done    bool
+}
+
+// NewSTREAM returns a new STREAM encrypting and decrypting chunks
+// with ChaCha20Poly1305 under key. The nonceprefix must be
+// NoncePrefixSize (7) byte long and, like any ChaCha20Poly1305 nonce,
+// must never be reused with the same key for a different message.
+func NewSTREAM(key *[32]byte, nonceprefix []byte) (*STREAM, error) {
+	if len(nonceprefix) != NoncePrefixSize {
+		return nil, errors.New("chacha20: nonce prefix must be 7 byte long")
+	}
+	s := &STREAM{aead: NewChaCha20Poly1305(key)}
+	copy(s.prefix[:], nonceprefix)
+	return s, nil
+}
+
+// nonce builds the per-chunk ChaCha20Poly1305 nonce for the current
+// counter and final flag.
+func (s *STREAM) nonce(final bool) [NonceSize]byte {
+	var nonce [NonceSize]byte
+	copy(nonce[:], s.prefix[:])
+	nonce[NoncePrefixSize] = byte(s.counter >> 24)
+	nonce[NoncePrefixSize+1] = byte(s.counter >> 16)
+	nonce[NoncePrefixSize+2] = byte(s.counter >> 8)
+	nonce[NoncePrefixSize+3] = byte(s.counter)
+	if final {
+		nonce[NoncePrefixSize+4] = 1
+	}
+	return nonce
+}
+
+// SealChunk encrypts and authenticates plaintext as the next chunk
+// of the stream, writing the result to the start of dst, which must
+// be at least len(plaintext)+TagSize byte long. final must be true for
+// (and only for) the last chunk of the message. SealChunk panics if
+// the STREAM has already sealed its final chunk or exhausted its
+// chunk counter.
+func (s *STREAM) SealChunk(dst, plaintext, ad []byte, final bool) []byte {
+	if err := s.checkState(final); err != nil {
+		panic(err.Error())
+	}
+	nonce := s.nonce(final)
+	out := s.aead.Seal(dst, nonce[:], plaintext, ad)
+	s.advance(final)
+	return out
+}
+
+// OpenChunk decrypts and authenticates ciphertext as the next chunk
+// of the stream, writing the plaintext to the start of dst, which
+// must be at least len(ciphertext)-TagSize byte long. final must match
+// the value passed to the corresponding SealChunk call - in
+// particular, opening a truncated stream with final set to true for
+// a chunk that was sealed with final set to false (or vice versa)
+// fails authentication.
+func (s *STREAM) OpenChunk(dst, ciphertext, ad []byte, final bool) ([]byte, error) {
+	if err := s.checkState(final); err != nil {
+		return nil, err
+	}
+	nonce := s.nonce(final)
+	out, err := s.aead.Open(dst, nonce[:], ciphertext, ad)
+	if err != nil {
+		return nil, err
+	}
+	s.advance(final)
+	return out, nil
+}
+
+// checkState reports whether the STREAM can process one more chunk
+// with the given final flag.
+func (s *STREAM) checkState(final bool) error {
+	if s.done {
+		return errStreamFinished
+	}
+	if !final && s.counter == maxStreamCounter {
+		return ErrStreamCounterOverflow
+	}
+	return nil
+}
+
+// advance moves to the next chunk counter, marking the STREAM done
+// once the final chunk has been processed.
+func (s *STREAM) advance(final bool) {
+	if final {
+		s.done = true
+		return
+	}
+	s.counter++
+}
@@ -0,0 +1,182 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package chacha20
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func newTestSTREAM(t *testing.T) (*STREAM, *STREAM) {
+	t.Helper()
+	var key [32]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	prefix := make([]byte, NoncePrefixSize)
+	for i := range prefix {
+		prefix[i] = byte(255 - i)
+	}
+
+	sealer, err := NewSTREAM(&key, prefix)
+	if err != nil {
+		t.Fatalf("failed to create sealing STREAM: %s", err)
+	}
+	opener, err := NewSTREAM(&key, prefix)
+	if err != nil {
+		t.Fatalf("failed to create opening STREAM: %s", err)
+	}
+	return sealer, opener
+}
+
+func seal(s *STREAM, plaintext []byte, final bool) []byte {
+	return s.SealChunk(make([]byte, len(plaintext)+TagSize), plaintext, nil, final)
+}
+
+func open(t *testing.T, s *STREAM, ciphertext []byte, final bool) ([]byte, error) {
+	t.Helper()
+	n := len(ciphertext) - TagSize
+	if n < 0 {
+		n = 0
+	}
+	return s.OpenChunk(make([]byte, n), ciphertext, nil, final)
+}
+
+func TestNewSTREAMRejectsWrongPrefixSize(t *testing.T) {
+	var key [32]byte
+	if _, err := NewSTREAM(&key, make([]byte, NoncePrefixSize-1)); err == nil {
+		t.Fatal("NewSTREAM accepted a too short nonce prefix")
+	}
+	if _, err := NewSTREAM(&key, make([]byte, NoncePrefixSize+1)); err == nil {
+		t.Fatal("NewSTREAM accepted a too long nonce prefix")
+	}
+}
+
+func TestSTREAMSealOpenRoundTrip(t *testing.T) {
+	sealer, opener := newTestSTREAM(t)
+
+	chunks := [][]byte{
+		[]byte("the quick brown fox "),
+		[]byte("jumps over "),
+		[]byte("the lazy dog"),
+	}
+
+	var ciphertexts [][]byte
+	for i, chunk := range chunks {
+		final := i == len(chunks)-1
+		ciphertexts = append(ciphertexts, seal(sealer, chunk, final))
+	}
+
+	for i, ciphertext := range ciphertexts {
+		final := i == len(chunks)-1
+		plaintext, err := open(t, opener, ciphertext, final)
+		if err != nil {
+			t.Fatalf("chunk %d: failed to open: %s", i, err)
+		}
+		if !bytes.Equal(plaintext, chunks[i]) {
+			t.Fatalf("chunk %d: got %q, expected %q", i, plaintext, chunks[i])
+		}
+	}
+}
+
+// TestSTREAMSingleChunkKnownAnswerVector pins SealChunk's wire format
+// - nonce = nonceprefix || big-endian counter || final byte - against
+// a ciphertext computed independently of this package: the same key,
+// nonce (built by hand from the prefix, a zero counter and a final
+// byte of 1) and plaintext were sealed with golang.org/x/crypto's
+// chacha20poly1305 AEAD directly. There is no independent
+// implementation of the STREAM chunking scheme itself (the Hoang-
+// Reyhanitabar-Rogaway-Vizár construction fixes no wire format), so
+// this only pins the one choice that's ours to get wrong: how the
+// per-chunk nonce is derived from the prefix, counter and final flag.
+func TestSTREAMSingleChunkKnownAnswerVector(t *testing.T) {
+	var key [32]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	prefix := make([]byte, NoncePrefixSize)
+	for i := range prefix {
+		prefix[i] = byte(255 - i)
+	}
+	msg := []byte("single chunk test")
+
+	wantCiphertext := fromHex("e25f57f682bb15a1b5d9d989963ca482b692a92b8de4b94a90e8f2e5a47ad8b55d")
+
+	s, err := NewSTREAM(&key, prefix)
+	if err != nil {
+		t.Fatalf("NewSTREAM: %s", err)
+	}
+	ciphertext := seal(s, msg, true)
+	if !bytes.Equal(ciphertext, wantCiphertext) {
+		t.Fatalf("SealChunk():\nFound   : %s\nExpected: %s", hex.EncodeToString(ciphertext), hex.EncodeToString(wantCiphertext))
+	}
+}
+
+func TestSTREAMRejectsTruncation(t *testing.T) {
+	sealer, opener := newTestSTREAM(t)
+
+	c0 := seal(sealer, []byte("chunk 0"), false)
+	seal(sealer, []byte("chunk 1"), true) // the true final chunk, dropped below
+
+	// An attacker drops the stream's real final chunk and tries to
+	// make the receiver treat c0 as the last chunk of a (truncated)
+	// message - this must fail, since c0 was sealed with final =
+	// false and therefore authenticates a different nonce than the
+	// one final = true asks the opener to verify against.
+	if _, err := open(t, opener, c0, true); err == nil {
+		t.Fatal("opener accepted a truncated stream as complete")
+	}
+}
+
+func TestSTREAMRejectsFinalFlagMismatch(t *testing.T) {
+	sealer, opener := newTestSTREAM(t)
+
+	ciphertext := seal(sealer, []byte("only chunk"), false)
+
+	if _, err := open(t, opener, ciphertext, true); err == nil {
+		t.Fatal("OpenChunk accepted a chunk with the wrong final flag")
+	}
+}
+
+func TestSTREAMRejectsReorderedChunks(t *testing.T) {
+	sealer, opener := newTestSTREAM(t)
+
+	c0 := seal(sealer, []byte("chunk 0"), false)
+	c1 := seal(sealer, []byte("chunk 1"), true)
+
+	// The opener still expects counter 0 next - feeding it c1
+	// (sealed with counter 1) must fail authentication.
+	if _, err := open(t, opener, c1, true); err == nil {
+		t.Fatal("OpenChunk accepted a chunk out of counter order")
+	}
+
+	if _, err := open(t, opener, c0, false); err != nil {
+		t.Fatalf("failed to open the correctly ordered chunk 0: %s", err)
+	}
+}
+
+func TestSTREAMPanicsAfterFinalChunk(t *testing.T) {
+	sealer, _ := newTestSTREAM(t)
+	seal(sealer, []byte("only chunk"), true)
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected SealChunk to panic after the final chunk")
+		}
+	}()
+	seal(sealer, []byte("too late"), false)
+}
+
+func TestSTREAMOpenErrorsAfterFinalChunk(t *testing.T) {
+	sealer, opener := newTestSTREAM(t)
+
+	c0 := seal(sealer, []byte("only chunk"), true)
+	if _, err := open(t, opener, c0, true); err != nil {
+		t.Fatalf("failed to open the final chunk: %s", err)
+	}
+	if _, err := open(t, opener, c0, true); err == nil {
+		t.Fatal("expected OpenChunk to fail once the STREAM has finished")
+	}
+}
@@ -8,6 +8,8 @@ import (
 	cryptorand "crypto/rand"
 	"errors"
 	"io"
+	mathbig "math/big"
+
 	big "github.com/ncw/gmp"
 )
 
@@ -26,6 +28,17 @@ func unmarshal(curve elliptic.Curve, data []byte) (x, y *big.Int) {
 	return
 }
 
+// stdBig converts a gmp big integer into the math/big.Int that
+// crypto/elliptic's Curve interface is hard-coded to. gmp.Int
+// mirrors math/big.Int's API (including Bytes/SetBytes) but is a
+// distinct type, so the two never satisfy each other's method
+// signatures directly - every coordinate decoded by unmarshal must
+// be converted through this before it can be passed to a Curve
+// method.
+func stdBig(x *big.Int) *mathbig.Int {
+	return new(mathbig.Int).SetBytes(x.Bytes())
+}
+
 // GenericCurve creates a new ecdh.KeyExchange with
 // generic elliptic.Curve implementations.
 func GenericCurve(c elliptic.Curve) KeyExchange {
@@ -55,7 +68,7 @@ func (g genericCurve) GenerateKey(rand io.Reader) (private PrivateKey, public Pu
 func (g genericCurve) PublicKey(private PrivateKey) (public PublicKey) {
 	N := g.curve.Params().N
 
-	if new(big.Int).SetBytes(private).Cmp(N) >= 0 {
+	if new(mathbig.Int).SetBytes(private).Cmp(N) >= 0 {
 		panic("ecdh: private key cannot used with given curve")
 	}
 
@@ -66,7 +79,10 @@ func (g genericCurve) PublicKey(private PrivateKey) (public PublicKey) {
 
 func (g genericCurve) Check(peersPublic PublicKey) (err error) {
 	x, y := unmarshal(g.curve, peersPublic)
-	if !g.curve.IsOnCurve(x, y) {
+	if x == nil || y == nil {
+		return errors.New("ecdh: invalid public key encoding")
+	}
+	if !g.curve.IsOnCurve(stdBig(x), stdBig(y)) {
 		err = errors.New("peer's public key is not on curve")
 	}
 	return
@@ -74,8 +90,11 @@ func (g genericCurve) Check(peersPublic PublicKey) (err error) {
 
 func (g genericCurve) ComputeSecret(private PrivateKey, peersPublic PublicKey) (secret []byte) {
 	x, y := unmarshal(g.curve, peersPublic)
+	if x == nil || y == nil {
+		panic("ecdh: peer's public key has the wrong size for this curve")
+	}
 
-	sX, _ := g.curve.ScalarMult(x, y, private)
+	sX, _ := g.curve.ScalarMult(stdBig(x), stdBig(y), private)
 
 	secret = sX.Bytes()
 	return
@@ -0,0 +1,277 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package ecdh
+
+import (
+	cryptorand "crypto/rand"
+	"errors"
+	"io"
+	"math/big"
+)
+
+// montgomeryParams holds the RFC 7748 constants of a Montgomery-form
+// curve used for X25519/X448 style Diffie-Hellman.
+type montgomeryParams struct {
+	size      int      // byte length of private/public keys
+	bits      int      // bit length of the field prime
+	p         *big.Int // the field prime
+	a24       *big.Int // (A - 2) / 4
+	basePoint byte     // u-coordinate of the base point
+	cofactor  int64    // the curve's cofactor
+}
+
+var params25519 = montgomeryParams{
+	size:      32,
+	bits:      255,
+	p:         mustPrime("57896044618658097711785492504343953926634992332820282019728792003956564819949"), // 2^255 - 19
+	a24:       big.NewInt(121665),
+	basePoint: 9,
+	cofactor:  8,
+}
+
+var params448 = montgomeryParams{
+	size: 56,
+	bits: 448,
+	p: mustPrime("726838724295606890549323807888004534353641360687318060281490199" +
+		"180612328166730772686396383698676545930088884461843637361053498018365439"), // 2^448 - 2^224 - 1
+	a24:       big.NewInt(39081),
+	basePoint: 5,
+	cofactor:  4,
+}
+
+func mustPrime(decimal string) *big.Int {
+	p, ok := new(big.Int).SetString(decimal, 10)
+	if !ok {
+		panic("ecdh: invalid field prime constant")
+	}
+	return p
+}
+
+// montgomeryCurve is a KeyExchange implementing X25519/X448-style
+// Diffie-Hellman directly from the RFC 7748 field arithmetic, without
+// depending on an elliptic.Curve or the gmp based genericCurve. Its
+// scalar multiplication follows the same sequence of field operations
+// regardless of the scalar (see the note on ladder below), but is NOT
+// constant-time: math/big's own arithmetic is variable-time in the
+// size of its operands, so a private scalar used with this
+// KeyExchange is not protected against a timing or cache side-channel
+// attacker.
+type montgomeryCurve struct {
+	params montgomeryParams
+}
+
+// X25519 creates a new ecdh.KeyExchange implementing Curve25519 as
+// specified in RFC 7748, using the Montgomery ladder directly on
+// u-coordinates rather than elliptic.Curve/ScalarMult. As described
+// on montgomeryCurve, this implementation is not constant-time.
+func X25519() KeyExchange { return montgomeryCurve{params: params25519} }
+
+// X448 creates a new ecdh.KeyExchange implementing Curve448 as
+// specified in RFC 7748. As described on montgomeryCurve, this
+// implementation is not constant-time.
+func X448() KeyExchange { return montgomeryCurve{params: params448} }
+
+func (c montgomeryCurve) clamp(k []byte) {
+	switch c.params.size {
+	case 32: // X25519, RFC 7748 section 5
+		k[0] &= 248
+		k[31] &= 127
+		k[31] |= 64
+	case 56: // X448, RFC 7748 section 5
+		k[0] &= 252
+		k[55] |= 128
+	}
+}
+
+func (c montgomeryCurve) GenerateKey(rand io.Reader) (private PrivateKey, public PublicKey, err error) {
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+	k := make([]byte, c.params.size)
+	if _, err = io.ReadFull(rand, k); err != nil {
+		return nil, nil, err
+	}
+	c.clamp(k)
+
+	u := make([]byte, c.params.size)
+	u[0] = c.params.basePoint
+	return k, c.scalarMult(k, u), nil
+}
+
+func (c montgomeryCurve) PublicKey(private PrivateKey) (public PublicKey) {
+	if len(private) != c.params.size {
+		panic("ecdh: private key has the wrong size for this curve")
+	}
+	u := make([]byte, c.params.size)
+	u[0] = c.params.basePoint
+	return c.scalarMult(private, u)
+}
+
+func (c montgomeryCurve) Check(peersPublic PublicKey) error {
+	if len(peersPublic) != c.params.size {
+		return errors.New("ecdh: peer's public key has the wrong size for this curve")
+	}
+	return nil
+}
+
+func (c montgomeryCurve) ComputeSecret(private PrivateKey, peersPublic PublicKey) (secret []byte) {
+	if len(private) != c.params.size {
+		panic("ecdh: private key has the wrong size for this curve")
+	}
+	if len(peersPublic) != c.params.size {
+		panic("ecdh: peer's public key has the wrong size for this curve")
+	}
+	return c.scalarMult(private, peersPublic)
+}
+
+// ComputeSecretSafe is like ComputeSecret but, instead of silently
+// returning it, reports the well known all-zero shared secret that
+// results from combining a clamped scalar with one of the curve's
+// low-order u-coordinates as an error.
+func (c montgomeryCurve) ComputeSecretSafe(private PrivateKey, peersPublic PublicKey) ([]byte, error) {
+	return rejectAllZeroSecret(c.ComputeSecret(private, peersPublic))
+}
+
+// Validate checks peersPublic against opts. RejectOutOfRange rejects
+// u-coordinates that are not fully reduced modulo the field prime.
+// RejectSmallSubgroup and RejectLowOrder both reject u-coordinates
+// whose order divides the curve's cofactor - i.e. the well known
+// low-order points - by multiplying peersPublic by the cofactor and
+// checking whether the result is the point at infinity; they are
+// equivalent for this curve and either may be set.
+func (c montgomeryCurve) Validate(peersPublic PublicKey, opts *ValidateOptions) error {
+	if opts == nil {
+		opts = &ValidateOptions{}
+	}
+	if len(peersPublic) != c.params.size {
+		return errors.New("ecdh: peer's public key has the wrong size for this curve")
+	}
+
+	u := c.decodeU(peersPublic)
+	if opts.RejectOutOfRange && u.Cmp(c.params.p) >= 0 {
+		return errors.New("ecdh: peer's public key is not fully reduced")
+	}
+	if opts.RejectSmallSubgroup || opts.RejectLowOrder {
+		_, z2 := c.ladder(big.NewInt(c.params.cofactor), u)
+		if z2.Sign() == 0 {
+			return errors.New("ecdh: peer's public key is a low-order point")
+		}
+	}
+	return nil
+}
+
+// decodeU parses a little-endian u-coordinate, masking any unused
+// high bits of the field representation as required by RFC 7748.
+func (c montgomeryCurve) decodeU(u []byte) *big.Int {
+	b := make([]byte, len(u))
+	copy(b, u)
+	if unused := uint(c.params.size*8 - c.params.bits); unused > 0 {
+		b[len(b)-1] &= 0xFF >> unused
+	}
+	return new(big.Int).SetBytes(reverseBytes(b))
+}
+
+// encodeU serializes x as a little-endian u-coordinate of the
+// curve's fixed byte size.
+func (c montgomeryCurve) encodeU(x *big.Int) []byte {
+	be := x.Bytes()
+	buf := make([]byte, c.params.size)
+	copy(buf[c.params.size-len(be):], be)
+	return reverseBytes(buf)
+}
+
+// scalarMult performs the Montgomery ladder scalar multiplication of
+// the u-coordinate u by the little-endian scalar k, returning the
+// resulting u-coordinate.
+func (c montgomeryCurve) scalarMult(k, u []byte) []byte {
+	kInt := new(big.Int).SetBytes(reverseBytes(k))
+	x2, z2 := c.ladder(kInt, c.decodeU(u))
+
+	p2 := new(big.Int).Sub(c.params.p, big.NewInt(2))
+	zInv := new(big.Int).Exp(z2, p2, c.params.p)
+	return c.encodeU(new(big.Int).Mod(new(big.Int).Mul(x2, zInv), c.params.p))
+}
+
+// ladder is the RFC 7748 section 5 Montgomery ladder. It is NOT a
+// constant-time implementation: the conditional swap below branches
+// on bits of k, and math/big's arithmetic is itself variable-time, so
+// this must not be relied on to resist timing or cache side-channel
+// attacks against the private scalar.
+func (c montgomeryCurve) ladder(k, u *big.Int) (x2, z2 *big.Int) {
+	p := c.params.p
+	x1 := u
+	x2, z2 = big.NewInt(1), big.NewInt(0)
+	x3, z3 := new(big.Int).Set(u), big.NewInt(1)
+	swap := uint(0)
+
+	for t := c.params.bits - 1; t >= 0; t-- {
+		kt := k.Bit(t)
+		swap ^= kt
+		if swap == 1 {
+			x2, x3 = x3, x2
+			z2, z3 = z3, z2
+		}
+		swap = kt
+
+		a := addMod(x2, z2, p)
+		aa := mulMod(a, a, p)
+		b := subMod(x2, z2, p)
+		bb := mulMod(b, b, p)
+		e := subMod(aa, bb, p)
+		cc := addMod(x3, z3, p)
+		d := subMod(x3, z3, p)
+		da := mulMod(d, a, p)
+		cb := mulMod(cc, b, p)
+
+		x3 = mulMod(addMod(da, cb, p), addMod(da, cb, p), p)
+		z3 = mulMod(x1, mulMod(subMod(da, cb, p), subMod(da, cb, p), p), p)
+		x2 = mulMod(aa, bb, p)
+		z2 = mulMod(e, addMod(aa, mulMod(c.params.a24, e, p), p), p)
+	}
+	if swap == 1 {
+		x2, x3 = x3, x2
+		z2, z3 = z3, z2
+	}
+	return x2, z2
+}
+
+func addMod(a, b, p *big.Int) *big.Int {
+	return new(big.Int).Mod(new(big.Int).Add(a, b), p)
+}
+
+func subMod(a, b, p *big.Int) *big.Int {
+	return new(big.Int).Mod(new(big.Int).Sub(a, b), p)
+}
+
+func mulMod(a, b, p *big.Int) *big.Int {
+	return new(big.Int).Mod(new(big.Int).Mul(a, b), p)
+}
+
+func reverseBytes(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i, v := range b {
+		out[len(b)-1-i] = v
+	}
+	return out
+}
+
+func allZero(b []byte) bool {
+	var v byte
+	for _, c := range b {
+		v |= c
+	}
+	return v == 0
+}
+
+// rejectAllZeroSecret is the shared implementation behind every
+// KeyExchange's ComputeSecretSafe: it reports the well known all-zero
+// shared secret that results from combining a private key with a
+// low-order (or, for genericCurve, point-at-infinity) peer public key
+// as an error instead of silently returning it.
+func rejectAllZeroSecret(secret []byte) ([]byte, error) {
+	if allZero(secret) {
+		return nil, errors.New("ecdh: computed shared secret is the all-zero point")
+	}
+	return secret, nil
+}
@@ -0,0 +1,150 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package ecdh
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func fromHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// curve25519LowOrderPoints are the 7 canonical low-order u-coordinates
+// for Curve25519 from libsodium's x25519_ref10.c blacklist:
+// https://github.com/jedisct1/libsodium/blob/65621a1059a37d/src/libsodium/crypto_scalarmult/curve25519/ref10/x25519_ref10.c#L11-L70
+var curve25519LowOrderPoints = []string{
+	"0000000000000000000000000000000000000000000000000000000000000000",
+	"0100000000000000000000000000000000000000000000000000000000000000",
+	"e0eb7a7c3b41b8ae1656e3faf19fc46ada098deb9c32b1fd866205165f49b800",
+	"5f9c95bca3508c24b1d0b1559c83ef5b04445cc4581c8e86d8224eddd09f1157",
+	"ecffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f",
+	"edffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f",
+	"eeffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f",
+}
+
+func TestMontgomeryCurveSharedSecretMatches(t *testing.T) {
+	for _, kx := range []KeyExchange{X25519(), X448()} {
+		private1, public1, err := kx.GenerateKey(nil)
+		if err != nil {
+			t.Fatalf("failed to generate key pair: %s", err)
+		}
+		private2, public2, err := kx.GenerateKey(nil)
+		if err != nil {
+			t.Fatalf("failed to generate key pair: %s", err)
+		}
+
+		secret1 := kx.ComputeSecret(private1, public2)
+		secret2 := kx.ComputeSecret(private2, public1)
+		if !bytes.Equal(secret1, secret2) {
+			t.Fatalf("shared secrets do not match: %x vs %x", secret1, secret2)
+		}
+		if allZero(secret1) {
+			t.Fatal("shared secret between two freshly generated keys should not be zero")
+		}
+	}
+}
+
+func TestMontgomeryCurvePublicKeyMatchesGenerateKey(t *testing.T) {
+	for _, kx := range []KeyExchange{X25519(), X448()} {
+		private, public, err := kx.GenerateKey(nil)
+		if err != nil {
+			t.Fatalf("failed to generate key pair: %s", err)
+		}
+		if !bytes.Equal(kx.PublicKey(private), public) {
+			t.Fatal("PublicKey(private) does not match the public key returned by GenerateKey")
+		}
+	}
+}
+
+func TestMontgomeryCurveCheckRejectsWrongSize(t *testing.T) {
+	if err := X25519().Check(make([]byte, 31)); err == nil {
+		t.Fatal("expected Check to reject a too short public key")
+	}
+	if err := X448().Check(make([]byte, 57)); err == nil {
+		t.Fatal("expected Check to reject a too long public key")
+	}
+}
+
+func TestX25519ValidateRejectsLowOrderPoint(t *testing.T) {
+	c := X25519().(montgomeryCurve)
+
+	// The all-zero u-coordinate is a canonical low-order point: every
+	// scalar multiple of it is again the identity, so it must be
+	// rejected by both RejectSmallSubgroup and its RejectLowOrder
+	// alias.
+	zero := make([]byte, 32)
+	if err := c.Validate(zero, &ValidateOptions{RejectSmallSubgroup: true}); err == nil {
+		t.Fatal("expected the zero point to be rejected by RejectSmallSubgroup")
+	}
+	if err := c.Validate(zero, &ValidateOptions{RejectLowOrder: true}); err == nil {
+		t.Fatal("expected the zero point to be rejected by RejectLowOrder")
+	}
+	if err := c.Check(zero); err != nil {
+		t.Fatalf("Check should only reject on size, not low-order points: %s", err)
+	}
+}
+
+func TestX25519ValidateRejectsKnownLowOrderPoints(t *testing.T) {
+	c := X25519().(montgomeryCurve)
+
+	for _, s := range curve25519LowOrderPoints {
+		u := fromHex(s)
+		if err := c.Validate(u, &ValidateOptions{RejectSmallSubgroup: true}); err == nil {
+			t.Errorf("expected low-order point %s to be rejected by RejectSmallSubgroup", s)
+		}
+		if err := c.Validate(u, &ValidateOptions{RejectLowOrder: true}); err == nil {
+			t.Errorf("expected low-order point %s to be rejected by RejectLowOrder", s)
+		}
+	}
+}
+
+func TestX25519ComputeSecretSafeRejectsKnownLowOrderPoints(t *testing.T) {
+	c := X25519().(montgomeryCurve)
+	private, _, err := c.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("failed to generate key pair: %s", err)
+	}
+
+	for _, s := range curve25519LowOrderPoints {
+		u := fromHex(s)
+		if _, err := c.ComputeSecretSafe(private, u); err == nil {
+			t.Errorf("expected ComputeSecretSafe to reject low-order point %s", s)
+		}
+	}
+}
+
+func TestX25519ValidateRejectsOutOfRangeCoordinate(t *testing.T) {
+	c := X25519().(montgomeryCurve)
+
+	tooBig := make([]byte, 32)
+	for i := range tooBig {
+		tooBig[i] = 0xFF
+	}
+	if err := c.Validate(tooBig, &ValidateOptions{RejectOutOfRange: true}); err == nil {
+		t.Fatal("expected an unreduced u-coordinate to be rejected")
+	}
+	if err := c.Validate(tooBig, &ValidateOptions{}); err != nil {
+		t.Fatalf("Validate without RejectOutOfRange should accept an unreduced coordinate: %s", err)
+	}
+}
+
+func TestMontgomeryCurveComputeSecretSafeRejectsLowOrderPoint(t *testing.T) {
+	for _, c := range []montgomeryCurve{X25519().(montgomeryCurve), X448().(montgomeryCurve)} {
+		private, _, err := c.GenerateKey(nil)
+		if err != nil {
+			t.Fatalf("failed to generate key pair: %s", err)
+		}
+		zero := make([]byte, c.params.size)
+		if _, err := c.ComputeSecretSafe(private, zero); err == nil {
+			t.Fatal("expected ComputeSecretSafe to reject the all-zero low-order point")
+		}
+	}
+}
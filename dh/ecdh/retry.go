@@ -0,0 +1,53 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package ecdh
+
+import (
+	"crypto/elliptic"
+	cryptorand "crypto/rand"
+	"io"
+	"time"
+
+	"github.com/EncEve/crypto/retry"
+)
+
+// GenericCurveWithBackoff creates a new ecdh.KeyExchange with a
+// generic elliptic.Curve implementation whose GenerateKey
+// transparently retries reads from a flaky rand source (e.g. a
+// hardware RNG over a socket) according to the given Backoff.
+func GenericCurveWithBackoff(c elliptic.Curve, b retry.Backoff) KeyExchange {
+	if c == nil {
+		panic("ecdh: curve is nil")
+	}
+	if b == nil {
+		panic("ecdh: backoff is nil")
+	}
+	return genericCurveRetry{genericCurve: genericCurve{curve: c}, backoff: b}
+}
+
+type genericCurveRetry struct {
+	genericCurve
+	backoff retry.Backoff
+}
+
+// GenerateKey behaves like genericCurve.GenerateKey but retries
+// according to g.backoff as long as rand keeps returning errors.
+func (g genericCurveRetry) GenerateKey(rand io.Reader) (private PrivateKey, public PublicKey, err error) {
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+
+	for attempt := 1; ; attempt++ {
+		private, public, err = g.genericCurve.GenerateKey(rand)
+		if err == nil {
+			return
+		}
+
+		delay := g.backoff.NextDelay(attempt, err)
+		if delay < 0 {
+			return
+		}
+		time.Sleep(delay)
+	}
+}
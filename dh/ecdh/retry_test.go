@@ -0,0 +1,72 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package ecdh
+
+import (
+	"crypto/elliptic"
+	cryptorand "crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/EncEve/crypto/retry"
+)
+
+// flakyReader fails the first n reads and then delegates to src.
+type flakyReader struct {
+	n   int
+	src interface {
+		Read(p []byte) (int, error)
+	}
+}
+
+func (f *flakyReader) Read(p []byte) (int, error) {
+	if f.n > 0 {
+		f.n--
+		return 0, errors.New("flaky reader: temporary failure")
+	}
+	return f.src.Read(p)
+}
+
+func TestGenericCurveWithBackoffRetriesUntilSuccess(t *testing.T) {
+	b := retry.NewExponential()
+	b.Base = time.Millisecond
+	b.Ceiling = time.Millisecond
+	b.Jitter = 0
+
+	kx := GenericCurveWithBackoff(elliptic.P256(), b)
+	rand := &flakyReader{n: 2, src: cryptorand.Reader}
+
+	private, public, err := kx.GenerateKey(rand)
+	if err != nil {
+		t.Fatalf("expected GenerateKey to eventually succeed, got: %s", err)
+	}
+	if private == nil || public == nil {
+		t.Fatal("expected non-nil private and public key")
+	}
+}
+
+func TestGenericCurveWithBackoffGivesUp(t *testing.T) {
+	b := retry.NewExponential()
+	b.Base = time.Millisecond
+	b.Ceiling = time.Millisecond
+	b.Jitter = 0
+	b.MaxAttempts = 2
+
+	kx := GenericCurveWithBackoff(elliptic.P256(), b)
+	rand := &flakyReader{n: 10, src: cryptorand.Reader}
+
+	if _, _, err := kx.GenerateKey(rand); err == nil {
+		t.Fatal("expected GenerateKey to give up and return an error")
+	}
+}
+
+func TestGenericCurveWithBackoffPanicsOnNilArgs(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected GenericCurveWithBackoff to panic on a nil curve")
+		}
+	}()
+	GenericCurveWithBackoff(nil, retry.NewExponential())
+}
@@ -0,0 +1,77 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package ecdh
+
+import (
+	"errors"
+	"math/big"
+)
+
+// ValidateOptions enables additional peer public-key checks beyond
+// plain curve-membership (as done by Check) for a KeyExchange's
+// Validate method.
+type ValidateOptions struct {
+	// RejectOutOfRange rejects public keys whose coordinates are
+	// not fully reduced modulo the curve's field prime.
+	RejectOutOfRange bool
+
+	// RejectSmallSubgroup rejects public keys in the curve's small
+	// subgroup - i.e. whose order divides the curve's cofactor -
+	// by multiplying the public key by the cofactor and checking
+	// the result against the point at infinity.
+	RejectSmallSubgroup bool
+
+	// RejectLowOrder is the Montgomery-curve (X25519/X448) name for
+	// the same check as RejectSmallSubgroup; the two are equivalent
+	// and either may be set. It exists because "low-order point" is
+	// the more familiar term for that family of curves.
+	RejectLowOrder bool
+}
+
+// Validate checks peersPublic against opts, in addition to the
+// plain curve-membership check already performed by Check.
+// elliptic.Curve's NIST P-curves all have cofactor 1, so the small-
+// subgroup check never rejects a point already accepted by Check;
+// it is implemented anyway so Validate behaves uniformly across
+// every KeyExchange returned by this package.
+func (g genericCurve) Validate(peersPublic PublicKey, opts *ValidateOptions) error {
+	if opts == nil {
+		opts = &ValidateOptions{}
+	}
+
+	gx, gy := unmarshal(g.curve, peersPublic)
+	if gx == nil || gy == nil {
+		return errors.New("ecdh: invalid public key encoding")
+	}
+	x, y := stdBig(gx), stdBig(gy)
+
+	if opts.RejectOutOfRange {
+		p := g.curve.Params().P
+		if x.Sign() < 0 || y.Sign() < 0 || x.Cmp(p) >= 0 || y.Cmp(p) >= 0 {
+			return errors.New("ecdh: public key coordinate out of range")
+		}
+	}
+	if !g.curve.IsOnCurve(x, y) {
+		return errors.New("peer's public key is not on curve")
+	}
+	if opts.RejectSmallSubgroup || opts.RejectLowOrder {
+		cx, cy := g.curve.ScalarMult(x, y, big.NewInt(genericCofactor).Bytes())
+		if cx.Sign() == 0 && cy.Sign() == 0 {
+			return errors.New("ecdh: public key is in the curve's small subgroup")
+		}
+	}
+	return nil
+}
+
+// genericCofactor is the cofactor of every NIST P-curve supported by
+// GenericCurve.
+const genericCofactor = 1
+
+// ComputeSecretSafe is like ComputeSecret but reports an all-zero
+// shared secret - which an on-curve but otherwise degenerate peer
+// public key can produce - as an error instead of silently returning
+// it to the caller.
+func (g genericCurve) ComputeSecretSafe(private PrivateKey, peersPublic PublicKey) ([]byte, error) {
+	return rejectAllZeroSecret(g.ComputeSecret(private, peersPublic))
+}
@@ -0,0 +1,51 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package ecdh
+
+import (
+	"crypto/elliptic"
+	"testing"
+)
+
+func TestGenericCurveValidateRejectsInvalidEncoding(t *testing.T) {
+	kx := GenericCurve(elliptic.P256())
+	if err := kx.(genericCurve).Validate(make([]byte, 3), nil); err == nil {
+		t.Fatal("expected Validate to reject a malformed public key encoding")
+	}
+}
+
+func TestGenericCurveValidateAcceptsFreshPublicKey(t *testing.T) {
+	kx := GenericCurve(elliptic.P256())
+	_, public, err := kx.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("failed to generate key pair: %s", err)
+	}
+	opts := &ValidateOptions{RejectOutOfRange: true, RejectSmallSubgroup: true}
+	if err := kx.(genericCurve).Validate(public, opts); err != nil {
+		t.Fatalf("expected Validate to accept a freshly generated public key: %s", err)
+	}
+}
+
+// TestGenericCurveComputeSecretSafeAcceptsNonDegenerateSecret only
+// covers the non-degenerate path: P-256 has cofactor 1, so there is
+// no non-infinity low-order public key to combine with a private key
+// and actually reach the all-zero secret that ComputeSecretSafe
+// guards against - unlike montgomeryCurve, which can and does test
+// that rejection directly with the curve's zero u-coordinate in
+// TestMontgomeryCurveComputeSecretSafeRejectsLowOrderPoint.
+func TestGenericCurveComputeSecretSafeAcceptsNonDegenerateSecret(t *testing.T) {
+	kx := GenericCurve(elliptic.P256())
+	private, public, err := kx.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("failed to generate key pair: %s", err)
+	}
+
+	secret, err := kx.(genericCurve).ComputeSecretSafe(private, public)
+	if err != nil {
+		t.Fatalf("unexpected error for a non-degenerate shared secret: %s", err)
+	}
+	if allZero(secret) {
+		t.Fatal("shared secret with a freshly generated peer key should not be zero")
+	}
+}
@@ -0,0 +1,257 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package noise
+
+import (
+	"crypto/cipher"
+	cryptorand "crypto/rand"
+
+	"github.com/EncEve/crypto/dh/ecdh"
+)
+
+// HandshakeState drives one Noise handshake. It is not safe for
+// concurrent use and must be discarded once Finished reports true.
+type HandshakeState struct {
+	ss *symmetricState
+
+	dh         ecdh.KeyExchange
+	randSource interface {
+		Read(p []byte) (int, error)
+	}
+
+	initiator bool
+	messages  [][]token
+	step      int
+
+	s, e   Keypair
+	rs, re ecdh.PublicKey
+
+	finished bool
+}
+
+// NewHandshakeState validates cfg and returns a HandshakeState ready
+// to exchange the first handshake message.
+func NewHandshakeState(cfg Config) (*HandshakeState, error) {
+	if cfg.DH == nil {
+		return nil, ErrConfig
+	}
+	if cfg.Pattern == IK && cfg.Initiator && cfg.RemoteStatic == nil {
+		return nil, ErrConfig
+	}
+	if cfg.StaticKeypair.Private == nil || cfg.StaticKeypair.Public == nil {
+		return nil, ErrConfig
+	}
+
+	sc, ok := scripts[cfg.Pattern]
+	if !ok {
+		return nil, ErrConfig
+	}
+
+	cipherFn := cfg.Cipher
+	if cipherFn == nil {
+		cipherFn = defaultCipher
+	}
+	hashFn := cfg.Hash
+	if hashFn == nil {
+		hashFn = defaultHash
+	}
+	rand := cfg.Rand
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+
+	hs := &HandshakeState{
+		ss:         newSymmetricState(sc.name, hashFn, cipherFn),
+		dh:         cfg.DH,
+		randSource: rand,
+		initiator:  cfg.Initiator,
+		messages:   sc.messages,
+		s:          cfg.StaticKeypair,
+		rs:         cfg.RemoteStatic,
+	}
+	hs.ss.mixHash(cfg.Prologue)
+
+	if sc.preMessageResponder {
+		if cfg.Initiator {
+			hs.ss.mixHash(cfg.RemoteStatic)
+		} else {
+			hs.ss.mixHash(cfg.StaticKeypair.Public)
+		}
+	}
+	return hs, nil
+}
+
+// Finished reports whether the handshake has completed and
+// CipherStates are available via WriteMessage/ReadMessage's return
+// value.
+func (hs *HandshakeState) Finished() bool { return hs.finished }
+
+// WriteMessage produces the next handshake message, appending it to
+// dst, and binds payload to it once a key has been established. On
+// the message that completes the pattern it additionally returns
+// the two transport CipherStates - (sender, receiver) from this
+// party's point of view.
+func (hs *HandshakeState) WriteMessage(dst, payload []byte) (message []byte, c1, c2 *CipherState, err error) {
+	if hs.finished {
+		return nil, nil, nil, ErrHandshakeFinished
+	}
+
+	tokens := hs.messages[hs.step]
+	message = dst
+	for _, t := range tokens {
+		switch t {
+		case tokenE:
+			priv, pub, genErr := hs.dh.GenerateKey(hs.randSource)
+			if genErr != nil {
+				return nil, nil, nil, genErr
+			}
+			hs.e = Keypair{Private: priv, Public: pub}
+			message = append(message, pub...)
+			hs.ss.mixHash(pub)
+		case tokenS:
+			message = append(message, hs.ss.encryptAndHash(hs.s.Public)...)
+		case tokenEE:
+			hs.ss.mixKey(hs.dh.ComputeSecret(hs.e.Private, hs.re))
+		case tokenES:
+			if hs.initiator {
+				hs.ss.mixKey(hs.dh.ComputeSecret(hs.e.Private, hs.rs))
+			} else {
+				hs.ss.mixKey(hs.dh.ComputeSecret(hs.s.Private, hs.re))
+			}
+		case tokenSE:
+			if hs.initiator {
+				hs.ss.mixKey(hs.dh.ComputeSecret(hs.s.Private, hs.re))
+			} else {
+				hs.ss.mixKey(hs.dh.ComputeSecret(hs.e.Private, hs.rs))
+			}
+		case tokenSS:
+			hs.ss.mixKey(hs.dh.ComputeSecret(hs.s.Private, hs.rs))
+		}
+	}
+	message = append(message, hs.ss.encryptAndHash(payload)...)
+
+	hs.step++
+	if hs.step == len(hs.messages) {
+		hs.finished = true
+		if hs.initiator {
+			c1, c2 = hs.ss.split()
+		} else {
+			c2, c1 = hs.ss.split()
+		}
+	}
+	return message, c1, c2, nil
+}
+
+// ReadMessage consumes the next handshake message and returns the
+// decrypted payload, appended to dst. As with WriteMessage, the
+// message that completes the pattern additionally returns the two
+// transport CipherStates - (sender, receiver) from this party's
+// point of view.
+func (hs *HandshakeState) ReadMessage(dst, message []byte) (payload []byte, c1, c2 *CipherState, err error) {
+	if hs.finished {
+		return nil, nil, nil, ErrHandshakeFinished
+	}
+
+	dhLen := len(hs.s.Public)
+	tokens := hs.messages[hs.step]
+	for _, t := range tokens {
+		switch t {
+		case tokenE:
+			if len(message) < dhLen {
+				return nil, nil, nil, ErrUnexpectedMessage
+			}
+			candidate := append(ecdh.PublicKey{}, message[:dhLen]...)
+			if err := hs.dh.Check(candidate); err != nil {
+				return nil, nil, nil, ErrInvalidPeerKey
+			}
+			hs.re = candidate
+			message = message[dhLen:]
+			hs.ss.mixHash(hs.re)
+		case tokenS:
+			n := dhLen
+			if hs.ss.hasKey {
+				n += hs.ss.cipherFn(&hs.ss.key).Overhead()
+			}
+			if len(message) < n {
+				return nil, nil, nil, ErrUnexpectedMessage
+			}
+			rs, decErr := hs.ss.decryptAndHash(message[:n])
+			if decErr != nil {
+				return nil, nil, nil, decErr
+			}
+			candidate := append(ecdh.PublicKey{}, rs...)
+			if err := hs.dh.Check(candidate); err != nil {
+				return nil, nil, nil, ErrInvalidPeerKey
+			}
+			hs.rs = candidate
+			message = message[n:]
+		case tokenEE:
+			hs.ss.mixKey(hs.dh.ComputeSecret(hs.e.Private, hs.re))
+		case tokenES:
+			if hs.initiator {
+				hs.ss.mixKey(hs.dh.ComputeSecret(hs.e.Private, hs.rs))
+			} else {
+				hs.ss.mixKey(hs.dh.ComputeSecret(hs.s.Private, hs.re))
+			}
+		case tokenSE:
+			if hs.initiator {
+				hs.ss.mixKey(hs.dh.ComputeSecret(hs.s.Private, hs.re))
+			} else {
+				hs.ss.mixKey(hs.dh.ComputeSecret(hs.e.Private, hs.rs))
+			}
+		case tokenSS:
+			hs.ss.mixKey(hs.dh.ComputeSecret(hs.s.Private, hs.rs))
+		}
+	}
+
+	if hs.ss.hasKey && len(message) < hs.ss.cipherFn(&hs.ss.key).Overhead() {
+		return nil, nil, nil, ErrUnexpectedMessage
+	}
+	plaintext, err := hs.ss.decryptAndHash(message)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	payload = append(dst, plaintext...)
+
+	hs.step++
+	if hs.step == len(hs.messages) {
+		hs.finished = true
+		if hs.initiator {
+			c1, c2 = hs.ss.split()
+		} else {
+			c2, c1 = hs.ss.split()
+		}
+	}
+	return payload, c1, c2, nil
+}
+
+// CipherState encrypts or decrypts transport messages with a fixed
+// key and a strictly increasing 64 bit nonce counter, as produced by
+// a completed HandshakeState.
+type CipherState struct {
+	cipherFn func(key *[32]byte) cipher.AEAD
+	key      [32]byte
+	nonce    uint64
+}
+
+// Encrypt seals plaintext, appending it to dst, using the next
+// nonce in sequence.
+func (c *CipherState) Encrypt(dst, ad, plaintext []byte) []byte {
+	aead := c.cipherFn(&c.key)
+	out := aead.Seal(make([]byte, len(plaintext)+aead.Overhead()), nonceBytes(c.nonce), plaintext, ad)
+	c.nonce++
+	return append(dst, out...)
+}
+
+// Decrypt opens ciphertext, appending the plaintext to dst, using
+// the next nonce in sequence.
+func (c *CipherState) Decrypt(dst, ad, ciphertext []byte) ([]byte, error) {
+	aead := c.cipherFn(&c.key)
+	plaintext, err := aead.Open(make([]byte, len(ciphertext)-aead.Overhead()), nonceBytes(c.nonce), ciphertext, ad)
+	if err != nil {
+		return nil, err
+	}
+	c.nonce++
+	return append(dst, plaintext...), nil
+}
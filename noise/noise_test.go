@@ -0,0 +1,193 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package noise
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"testing"
+
+	"github.com/EncEve/crypto/dh/ecdh"
+)
+
+func genKeypair(t *testing.T, dh ecdh.KeyExchange) Keypair {
+	t.Helper()
+	private, public, err := dh.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("failed to generate key pair: %s", err)
+	}
+	return Keypair{Private: private, Public: public}
+}
+
+// runHandshake drives hs1 (initiator) and hs2 (responder) to
+// completion and returns each side's (sender, receiver) CipherStates.
+func runHandshake(t *testing.T, hs1, hs2 *HandshakeState) (s1, r1, s2, r2 *CipherState) {
+	t.Helper()
+
+	for !hs1.Finished() || !hs2.Finished() {
+		if !hs1.Finished() {
+			msg, wc1, wc2, err := hs1.WriteMessage(nil, nil)
+			if err != nil {
+				t.Fatalf("initiator WriteMessage: %s", err)
+			}
+			if wc1 != nil {
+				s1, r1 = wc1, wc2
+			}
+			_, rc1, rc2, err := hs2.ReadMessage(nil, msg)
+			if err != nil {
+				t.Fatalf("responder ReadMessage: %s", err)
+			}
+			if rc1 != nil {
+				s2, r2 = rc1, rc2
+			}
+		}
+		if !hs2.Finished() {
+			msg, wc1, wc2, err := hs2.WriteMessage(nil, nil)
+			if err != nil {
+				t.Fatalf("responder WriteMessage: %s", err)
+			}
+			if wc1 != nil {
+				s2, r2 = wc1, wc2
+			}
+			_, rc1, rc2, err := hs1.ReadMessage(nil, msg)
+			if err != nil {
+				t.Fatalf("initiator ReadMessage: %s", err)
+			}
+			if rc1 != nil {
+				s1, r1 = rc1, rc2
+			}
+		}
+	}
+	return
+}
+
+func TestXXHandshakeDerivesMatchingTransportKeys(t *testing.T) {
+	dh := ecdh.GenericCurve(elliptic.P256())
+	hs1, err := NewHandshakeState(Config{Pattern: XX, Initiator: true, DH: dh, StaticKeypair: genKeypair(t, dh)})
+	if err != nil {
+		t.Fatalf("NewHandshakeState: %s", err)
+	}
+	hs2, err := NewHandshakeState(Config{Pattern: XX, Initiator: false, DH: dh, StaticKeypair: genKeypair(t, dh)})
+	if err != nil {
+		t.Fatalf("NewHandshakeState: %s", err)
+	}
+
+	s1, r1, s2, r2 := runHandshake(t, hs1, hs2)
+
+	ciphertext := s1.Encrypt(nil, []byte("ad"), []byte("hello, responder"))
+	plaintext, err := r2.Decrypt(nil, []byte("ad"), ciphertext)
+	if err != nil {
+		t.Fatalf("responder failed to decrypt initiator's message: %s", err)
+	}
+	if !bytes.Equal(plaintext, []byte("hello, responder")) {
+		t.Fatalf("got plaintext %q", plaintext)
+	}
+
+	ciphertext = s2.Encrypt(nil, []byte("ad"), []byte("hello, initiator"))
+	plaintext, err = r1.Decrypt(nil, []byte("ad"), ciphertext)
+	if err != nil {
+		t.Fatalf("initiator failed to decrypt responder's message: %s", err)
+	}
+	if !bytes.Equal(plaintext, []byte("hello, initiator")) {
+		t.Fatalf("got plaintext %q", plaintext)
+	}
+}
+
+func TestIKHandshakeCarriesHandshakePayload(t *testing.T) {
+	dh := ecdh.GenericCurve(elliptic.P256())
+	respStatic := genKeypair(t, dh)
+
+	hs1, err := NewHandshakeState(Config{
+		Pattern: IK, Initiator: true, DH: dh,
+		StaticKeypair: genKeypair(t, dh),
+		RemoteStatic:  respStatic.Public,
+	})
+	if err != nil {
+		t.Fatalf("NewHandshakeState: %s", err)
+	}
+	hs2, err := NewHandshakeState(Config{Pattern: IK, Initiator: false, DH: dh, StaticKeypair: respStatic})
+	if err != nil {
+		t.Fatalf("NewHandshakeState: %s", err)
+	}
+
+	msg1, _, _, err := hs1.WriteMessage(nil, []byte("early data"))
+	if err != nil {
+		t.Fatalf("WriteMessage: %s", err)
+	}
+	payload, _, _, err := hs2.ReadMessage(nil, msg1)
+	if err != nil {
+		t.Fatalf("ReadMessage: %s", err)
+	}
+	if !bytes.Equal(payload, []byte("early data")) {
+		t.Fatalf("got payload %q", payload)
+	}
+
+	msg2, _, r2, err := hs2.WriteMessage(nil, nil)
+	if err != nil {
+		t.Fatalf("WriteMessage: %s", err)
+	}
+	if _, s1, r1, err := hs1.ReadMessage(nil, msg2); err != nil {
+		t.Fatalf("ReadMessage: %s", err)
+	} else if s1 == nil || r1 == nil {
+		t.Fatal("expected initiator to be finished")
+	} else {
+		ciphertext := s1.Encrypt(nil, nil, []byte("transport"))
+		plaintext, err := r2.Decrypt(nil, nil, ciphertext)
+		if err != nil {
+			t.Fatalf("failed to decrypt transport message: %s", err)
+		}
+		if !bytes.Equal(plaintext, []byte("transport")) {
+			t.Fatalf("got plaintext %q", plaintext)
+		}
+	}
+}
+
+func TestNewHandshakeStateRejectsMissingConfig(t *testing.T) {
+	if _, err := NewHandshakeState(Config{}); err != ErrConfig {
+		t.Fatalf("expected ErrConfig for an empty Config, got %v", err)
+	}
+
+	dh := ecdh.GenericCurve(elliptic.P256())
+	if _, err := NewHandshakeState(Config{Pattern: IK, Initiator: true, DH: dh, StaticKeypair: genKeypair(t, dh)}); err != ErrConfig {
+		t.Fatalf("expected ErrConfig when IK initiator has no RemoteStatic, got %v", err)
+	}
+}
+
+func TestReadMessageRejectsMalformedPeerKey(t *testing.T) {
+	dh := ecdh.GenericCurve(elliptic.P256())
+	hs1, _ := NewHandshakeState(Config{Pattern: XX, Initiator: true, DH: dh, StaticKeypair: genKeypair(t, dh)})
+	hs2, _ := NewHandshakeState(Config{Pattern: XX, Initiator: false, DH: dh, StaticKeypair: genKeypair(t, dh)})
+
+	msg, _, _, err := hs1.WriteMessage(nil, nil)
+	if err != nil {
+		t.Fatalf("WriteMessage: %s", err)
+	}
+
+	// The first XX message is just the initiator's ephemeral public
+	// key. Keep the uncompressed-point format byte but zero out the
+	// coordinates, so decoding succeeds but Check rejects the point
+	// as not lying on the curve - ReadMessage must return an error
+	// instead of panicking inside ComputeSecret further down the
+	// pattern.
+	malformed := append([]byte{}, msg...)
+	for i := 1; i < len(malformed); i++ {
+		malformed[i] = 0
+	}
+
+	if _, _, _, err := hs2.ReadMessage(nil, malformed); err != ErrInvalidPeerKey {
+		t.Fatalf("expected ErrInvalidPeerKey, got %v", err)
+	}
+}
+
+func TestWriteMessageAfterFinishFails(t *testing.T) {
+	dh := ecdh.GenericCurve(elliptic.P256())
+	hs1, _ := NewHandshakeState(Config{Pattern: XX, Initiator: true, DH: dh, StaticKeypair: genKeypair(t, dh)})
+	hs2, _ := NewHandshakeState(Config{Pattern: XX, Initiator: false, DH: dh, StaticKeypair: genKeypair(t, dh)})
+
+	runHandshake(t, hs1, hs2)
+
+	if _, _, _, err := hs1.WriteMessage(nil, nil); err != ErrHandshakeFinished {
+		t.Fatalf("expected ErrHandshakeFinished, got %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package noise
+
+import (
+	"crypto/cipher"
+	"hash"
+)
+
+// symmetricState tracks the running handshake hash and chaining key
+// and, once a DH result has been mixed in, the current encryption
+// key - mirroring the Noise spec's SymmetricState object.
+type symmetricState struct {
+	hashFn   func() hash.Hash
+	cipherFn func(key *[32]byte) cipher.AEAD
+
+	h  []byte
+	ck []byte
+
+	hasKey bool
+	key    [32]byte
+	nonce  uint64
+}
+
+func newSymmetricState(name string, hashFn func() hash.Hash, cipherFn func(key *[32]byte) cipher.AEAD) *symmetricState {
+	hashLen := hashFn().Size()
+
+	h := make([]byte, hashLen)
+	if len(name) <= hashLen {
+		copy(h, name)
+	} else {
+		sum := hashFn()
+		sum.Write([]byte(name))
+		h = sum.Sum(nil)
+	}
+
+	ck := make([]byte, len(h))
+	copy(ck, h)
+
+	ss := &symmetricState{hashFn: hashFn, cipherFn: cipherFn, h: h, ck: ck}
+	return ss
+}
+
+func (ss *symmetricState) mixHash(data []byte) {
+	sum := ss.hashFn()
+	sum.Write(ss.h)
+	sum.Write(data)
+	ss.h = sum.Sum(nil)
+}
+
+// mixKey absorbs a DH output into the chaining key and derives a
+// fresh encryption key from it, via the two-output HKDF used
+// throughout the Noise spec.
+func (ss *symmetricState) mixKey(inputKeyMaterial []byte) {
+	ck, k := hkdf2(ss.hashFn, ss.ck, inputKeyMaterial)
+	ss.ck = ck
+	copy(ss.key[:], k)
+	ss.hasKey = true
+	ss.nonce = 0
+}
+
+// encryptAndHash encrypts plaintext (once a key has been
+// established; otherwise it is passed through unmodified) binding
+// it to the running handshake hash, and mixes the resulting
+// ciphertext into that hash.
+func (ss *symmetricState) encryptAndHash(plaintext []byte) []byte {
+	var ciphertext []byte
+	if ss.hasKey {
+		aead := ss.cipherFn(&ss.key)
+		ciphertext = aead.Seal(make([]byte, len(plaintext)+aead.Overhead()), nonceBytes(ss.nonce), plaintext, ss.h)
+		ss.nonce++
+	} else {
+		ciphertext = plaintext
+	}
+	ss.mixHash(ciphertext)
+	return ciphertext
+}
+
+// decryptAndHash reverses encryptAndHash.
+func (ss *symmetricState) decryptAndHash(ciphertext []byte) ([]byte, error) {
+	var (
+		plaintext []byte
+		err       error
+	)
+	if ss.hasKey {
+		aead := ss.cipherFn(&ss.key)
+		plaintext, err = aead.Open(make([]byte, len(ciphertext)-aead.Overhead()), nonceBytes(ss.nonce), ciphertext, ss.h)
+		if err != nil {
+			return nil, err
+		}
+		ss.nonce++
+	} else {
+		plaintext = ciphertext
+	}
+	ss.mixHash(ciphertext)
+	return plaintext, nil
+}
+
+// split derives the pair of transport CipherStates from the final
+// chaining key, one per direction.
+func (ss *symmetricState) split() (c1, c2 *CipherState) {
+	k1, k2 := hkdf2(ss.hashFn, ss.ck, nil)
+	c1 = &CipherState{cipherFn: ss.cipherFn}
+	copy(c1.key[:], k1)
+	c2 = &CipherState{cipherFn: ss.cipherFn}
+	copy(c2.key[:], k2)
+	return
+}
+
+// nonceBytes encodes a 64 bit Noise nonce as the 96 bit nonce
+// required by the AEADs in this module: 4 zero bytes followed by
+// the counter, little-endian, as specified by the Noise framework.
+func nonceBytes(n uint64) []byte {
+	nonce := make([]byte, 12)
+	for i := 0; i < 8; i++ {
+		nonce[4+i] = byte(n >> (8 * uint(i)))
+	}
+	return nonce
+}
+
+// hkdf2 implements the 2-output HKDF used by the Noise spec: HKDF-
+// Extract with salt=chainingKey, ikm=inputKeyMaterial, followed by
+// HKDF-Expand into two hashLen-sized outputs.
+func hkdf2(hashFn func() hash.Hash, chainingKey, inputKeyMaterial []byte) (output1, output2 []byte) {
+	prk := hmacSum(hashFn, chainingKey, inputKeyMaterial)
+	output1 = hmacSum(hashFn, prk, []byte{1})
+	output2 = hmacSum(hashFn, prk, append(append([]byte{}, output1...), 2))
+	return
+}
+
+// hmacSum computes an HMAC over data using hashFn, constructing a
+// fresh hash.Hash for every inner/outer digest instead of relying on
+// Hash.Reset - not every hash.Hash in this module supports Reset
+// (blake2s notably does not), so plain crypto/hmac cannot be used
+// here.
+func hmacSum(hashFn func() hash.Hash, key, data []byte) []byte {
+	blockSize := hashFn().BlockSize()
+
+	if len(key) > blockSize {
+		sum := hashFn()
+		sum.Write(key)
+		key = sum.Sum(nil)
+	}
+	padded := make([]byte, blockSize)
+	copy(padded, key)
+
+	ipad := make([]byte, blockSize)
+	opad := make([]byte, blockSize)
+	for i, b := range padded {
+		ipad[i] = b ^ 0x36
+		opad[i] = b ^ 0x5c
+	}
+
+	inner := hashFn()
+	inner.Write(ipad)
+	inner.Write(data)
+	innerSum := inner.Sum(nil)
+
+	outer := hashFn()
+	outer.Write(opad)
+	outer.Write(innerSum)
+	return outer.Sum(nil)
+}
@@ -0,0 +1,100 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+// Package retry provides a reusable retry/backoff policy for
+// operations that may transparently fail and should be retried,
+// such as reading from a flaky entropy source or a network backed
+// key exchange. It does not retry anything itself - callers drive
+// the loop and ask a Backoff for the next delay.
+package retry
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Backoff computes the delay before the next retry attempt of some
+// operation. Implementations are called once per failed attempt.
+type Backoff interface {
+	// NextDelay returns the delay to wait before retrying after the
+	// attempt'th failed attempt (attempts are counted from 1) that
+	// failed with lastErr. A negative return value tells the caller
+	// to stop retrying.
+	NextDelay(attempt int, lastErr error) time.Duration
+}
+
+// RetryAfter can be implemented by an error to suggest an explicit
+// delay before the next retry attempt, overriding whatever delay the
+// Backoff would otherwise compute.
+type RetryAfter interface {
+	RetryAfter() time.Duration
+}
+
+// Exponential is a truncated-exponential Backoff: the n-th attempt
+// waits min(Ceiling, Base*2^(n-1)) plus a random jitter in
+// [0, Jitter). A zero-valued Exponential is not ready to use - call
+// NewExponential instead. Exponential's NextDelay is safe for
+// concurrent use, so a single Exponential may be shared across
+// goroutines - e.g. by a KeyExchange returned from
+// ecdh.GenericCurveWithBackoff whose GenerateKey is called
+// concurrently.
+type Exponential struct {
+	Base        time.Duration // The delay of the first attempt
+	Ceiling     time.Duration // The maximal delay between two attempts
+	Jitter      time.Duration // The upper bound of the random jitter added to every delay
+	MaxAttempts int           // The maximal number of attempts, 0 means unlimited
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// NewExponential returns a new Exponential backoff with a ceiling of
+// 10s, a base delay of 100ms, per-attempt jitter of up to 1s and an
+// unlimited number of attempts.
+func NewExponential() *Exponential {
+	return &Exponential{
+		Base:    100 * time.Millisecond,
+		Ceiling: 10 * time.Second,
+		Jitter:  time.Second,
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// NextDelay implements the Backoff interface.
+func (e *Exponential) NextDelay(attempt int, lastErr error) time.Duration {
+	if e.MaxAttempts > 0 && attempt >= e.MaxAttempts {
+		return -1
+	}
+	if ra, ok := lastErr.(RetryAfter); ok {
+		return ra.RetryAfter()
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := e.Ceiling
+	if shift := uint(attempt - 1); shift < 32 {
+		delay = e.Base * time.Duration(uint64(1)<<shift)
+	}
+	if delay <= 0 || delay > e.Ceiling {
+		delay = e.Ceiling
+	}
+	if e.Jitter > 0 {
+		delay += time.Duration(e.jitter(int64(e.Jitter)))
+	}
+	return delay
+}
+
+// jitter returns a random number in [0, n) using e.rand, guarded by
+// e.mu since math/rand.Rand is not safe for concurrent use.
+func (e *Exponential) jitter(n int64) int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	r := e.rand
+	if r == nil {
+		r = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return r.Int63n(n)
+}
@@ -0,0 +1,80 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package retry
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExponentialRespectsCeiling(t *testing.T) {
+	e := NewExponential()
+	e.Jitter = 0
+	e.Ceiling = 2 * time.Second
+
+	for attempt := 1; attempt <= 10; attempt++ {
+		if d := e.NextDelay(attempt, nil); d > e.Ceiling {
+			t.Fatalf("attempt %d: delay %s exceeds ceiling %s", attempt, d, e.Ceiling)
+		}
+	}
+}
+
+func TestExponentialGrows(t *testing.T) {
+	e := NewExponential()
+	e.Jitter = 0
+	e.Ceiling = time.Hour
+
+	first := e.NextDelay(1, nil)
+	second := e.NextDelay(2, nil)
+	if second <= first {
+		t.Fatalf("expected delay to grow: attempt 1 = %s, attempt 2 = %s", first, second)
+	}
+}
+
+func TestExponentialMaxAttemptsStops(t *testing.T) {
+	e := NewExponential()
+	e.MaxAttempts = 3
+
+	if d := e.NextDelay(3, nil); d >= 0 {
+		t.Fatalf("expected a negative delay once MaxAttempts is reached, got %s", d)
+	}
+}
+
+type retryAfterError struct{ after time.Duration }
+
+func (e retryAfterError) Error() string            { return "retry after hint" }
+func (e retryAfterError) RetryAfter() time.Duration { return e.after }
+
+func TestExponentialHonorsRetryAfterHint(t *testing.T) {
+	e := NewExponential()
+	e.Jitter = 0
+
+	hint := 42 * time.Millisecond
+	if d := e.NextDelay(1, retryAfterError{after: hint}); d != hint {
+		t.Fatalf("expected the RetryAfter hint %s to be used, got %s", hint, d)
+	}
+	if d := e.NextDelay(1, errors.New("no hint")); d != e.Base {
+		t.Fatalf("expected the base delay %s, got %s", e.Base, d)
+	}
+}
+
+// TestExponentialConcurrentNextDelay exercises NextDelay from many
+// goroutines at once, as happens when a single Exponential is shared
+// by a KeyExchange returned from ecdh.GenericCurveWithBackoff. Run
+// with -race to catch a regression of the data race on e.rand.
+func TestExponentialConcurrentNextDelay(t *testing.T) {
+	e := NewExponential()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(attempt int) {
+			defer wg.Done()
+			e.NextDelay(attempt%10+1, nil)
+		}(i)
+	}
+	wg.Wait()
+}